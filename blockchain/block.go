@@ -1,8 +1,8 @@
 package blockchain
 
 import (
-	"bytes"
-	"encoding/gob"
+	"errors"
+	"fmt"
 	"runtime/debug"
 	"time"
 
@@ -19,9 +19,40 @@ type Block struct {
 	Height      int
 }
 
+// MaxBlockSize 區塊中所有交易序列化後的位元組總和上限, 超過的區塊無效
+const MaxBlockSize = 1000000
+
+// TransactionsSize 交易序列化後的位元組總和
+func TransactionsSize(txs []*Transaction) int {
+	size := 0
+	for _, tx := range txs {
+		size += tx.Size()
+	}
+	return size
+}
+
+// ValidateBlock 不需要鏈上資料的區塊檢查: 工作量證明、大小上限, 以及只有第一筆交易是 coinbase
+func ValidateBlock(block *Block) error {
+	if !NewProof(block).Validate() {
+		return errors.New("block hash does not meet the proof of work")
+	}
+	if TransactionsSize(block.Transaction) > MaxBlockSize {
+		return errors.New("block exceeds MaxBlockSize")
+	}
+	if len(block.Transaction) == 0 || !block.Transaction[0].IsCoinbase() {
+		return errors.New("block does not start with a coinbase transaction")
+	}
+	for _, tx := range block.Transaction[1:] {
+		if tx.IsCoinbase() {
+			return errors.New("block has more than one coinbase transaction")
+		}
+	}
+	return nil
+}
+
 // Genesis 創建初始區塊
 func Genesis(coinbase *Transaction) *Block {
-	return CreateBlock([]*Transaction{coinbase}, []byte{},0)
+	return CreateBlock([]*Transaction{coinbase}, []byte{}, 0)
 }
 
 // HashTransactions 將 transaction 與 prev hash 做一次 hash
@@ -56,29 +87,30 @@ func CreateBlock(txs []*Transaction, prevHash []byte, height int) *Block {
 
 // Serialize ...
 func (b *Block) Serialize() []byte {
-	var res bytes.Buffer
+	var e encoder
 
-	encoder := gob.NewEncoder(&res)
-	err := encoder.Encode(b)
-	if err != nil {
-		log.Error().Msgf("Fail to encode from block")
-		return []byte{}
+	e.writeByte(blockEncodingVersion)
+	e.writeUint64(b.Timestamp)
+	e.writeBytes(b.Hash)
+	e.writeUvarint(uint64(len(b.Transaction)))
+	for _, tx := range b.Transaction {
+		e.writeBytes(tx.Serialize())
 	}
+	e.writeBytes(b.PrevHash)
+	e.writeVarint(int64(b.Nonce))
+	e.writeVarint(int64(b.Height))
 
-	return res.Bytes()
+	return e.Bytes()
 }
 
-// Deserialize ...
-func Deserialize(data []byte) *Block {
-	var block Block
-
-	decoder := gob.NewDecoder(bytes.NewReader(data))
-	err := decoder.Decode(&block)
+// Deserialize 資料無法解碼時回傳錯誤, 不會回傳只解碼一部分的區塊
+func Deserialize(data []byte) (*Block, error) {
+	block, err := DecodeBlock(data)
 	if err != nil {
-		log.Error().Msgf("Fail to decode to block")
+		return nil, fmt.Errorf("fail to decode block: %s", err)
 	}
 
-	return &block
+	return block, nil
 }
 
 // ErrHandler ...
@@ -0,0 +1,146 @@
+package blockchain_test
+
+import (
+	"blockchain/blockchain"
+	"blockchain/blockchain/chaintest"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestNonStandardValidInBlock 不符合 standardness 的交易仍然符合共識規則, 可以被挖進區塊並通過區塊驗證
+func TestNonStandardValidInBlock(t *testing.T) {
+	chain, w := chaintest.New(t)
+	tx := chaintest.SpendGenesis(t, chain, w, 1, 99)
+
+	if err := blockchain.DefaultPolicy().CheckStandard(tx); err == nil || !strings.Contains(err.Error(), "dust") {
+		t.Fatalf("dust transaction passed the policy: %v", err)
+	}
+	if !chain.VerifyTransactions([]*blockchain.Transaction{tx}) {
+		t.Fatal("dust transaction does not verify under consensus rules")
+	}
+
+	block := chain.MineBlock([]*blockchain.Transaction{blockchain.CoinbaseTx(string(w.Address()), "", 0), tx})
+	if err := chain.VerifyBlock(block); err != nil {
+		t.Fatalf("block with a non-standard transaction is invalid: %v", err)
+	}
+	if _, err := chain.FindTransaction(tx.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestTxIDExcludesSignatures 簽署前後交易 ID 相同, 修改簽章也不會改變 ID
+func TestTxIDExcludesSignatures(t *testing.T) {
+	chain, w := chaintest.New(t)
+	tx := chaintest.SpendGenesis(t, chain, w, 100)
+
+	unsigned := *tx
+	unsigned.Inputs = []blockchain.TxInput{tx.Inputs[0]}
+	unsigned.Inputs[0].Signature = nil
+	if !bytes.Equal(unsigned.Hash(), tx.ID) || !bytes.Equal(tx.Hash(), tx.ID) {
+		t.Fatal("signing changed the transaction ID")
+	}
+
+	tx.Inputs[0].Signature = append([]byte{}, tx.Inputs[0].Signature...)
+	tx.Inputs[0].Signature[0] ^= 1
+	if !bytes.Equal(tx.Hash(), tx.ID) {
+		t.Error("changing a signature changed the transaction ID")
+	}
+}
+
+// TestRejectsMismatchedTxID 交易 ID 與內容的雜湊不符時, 單筆交易與區塊都無法通過驗證
+func TestRejectsMismatchedTxID(t *testing.T) {
+	chain, w := chaintest.New(t)
+	tx := chaintest.SpendGenesis(t, chain, w, 100)
+	tx.ID = bytes.Repeat([]byte{1}, 32)
+
+	if chain.VerifyTransaction(tx) {
+		t.Error("transaction with a forged ID verifies")
+	}
+
+	coinbase := blockchain.CoinbaseTx(string(w.Address()), "", 0)
+	coinbase.ID = bytes.Repeat([]byte{2}, 32)
+	block := blockchain.CreateBlock([]*blockchain.Transaction{coinbase}, chain.LastHash, 1)
+	if err := chain.VerifyBlock(block); err == nil {
+		t.Error("block with a forged coinbase ID verifies")
+	}
+}
+
+// TestRejectsInvalidValues 建立金額、負數輸出與重複花費不符合共識規則
+func TestRejectsInvalidValues(t *testing.T) {
+	chain, w := chaintest.New(t)
+
+	cases := []struct {
+		name string
+		txs  func() []*blockchain.Transaction
+	}{
+		{"outputs exceed inputs", func() []*blockchain.Transaction {
+			return []*blockchain.Transaction{chaintest.SpendGenesis(t, chain, w, 101)}
+		}},
+		{"negative output", func() []*blockchain.Transaction {
+			return []*blockchain.Transaction{chaintest.SpendGenesis(t, chain, w, -1, 101)}
+		}},
+		{"output above MaxMoney", func() []*blockchain.Transaction {
+			return []*blockchain.Transaction{chaintest.SpendGenesis(t, chain, w, blockchain.MaxMoney+1)}
+		}},
+		{"same output spent twice in one batch", func() []*blockchain.Transaction {
+			return []*blockchain.Transaction{chaintest.SpendGenesis(t, chain, w, 100), chaintest.SpendGenesis(t, chain, w, 90)}
+		}},
+	}
+	for _, c := range cases {
+		if chain.VerifyTransactions(c.txs()) {
+			t.Errorf("%s: transactions verify", c.name)
+		}
+	}
+}
+
+// TestRejectsSpentOutput 已在鏈上花費的輸出不能再花費, 但另一條分支上的花費不影響此分支
+func TestRejectsSpentOutput(t *testing.T) {
+	chain, w := chaintest.New(t)
+	genesis := chain.LastHash
+
+	first := chaintest.SpendGenesis(t, chain, w, 100)
+	second := chaintest.SpendGenesis(t, chain, w, 90)
+	chain.MineBlock([]*blockchain.Transaction{blockchain.CoinbaseTx(string(w.Address()), "", 0), first})
+
+	if chain.VerifyTransaction(second) {
+		t.Error("transaction spending a spent output verifies")
+	}
+
+	coinbase := blockchain.CoinbaseTx(string(w.Address()), "", 10)
+	block := blockchain.CreateBlock([]*blockchain.Transaction{coinbase, second}, chain.LastHash, 2)
+	if err := chain.VerifyBlock(block); err == nil {
+		t.Error("block spending a spent output verifies")
+	}
+
+	fork := blockchain.CreateBlock([]*blockchain.Transaction{coinbase, second}, genesis, 1)
+	if err := chain.VerifyBlock(fork); err != nil {
+		t.Errorf("spend on another branch invalidates the fork: %v", err)
+	}
+}
+
+// TestCoinbaseValue coinbase 最多領取區塊獎勵加上區塊中交易的手續費
+func TestCoinbaseValue(t *testing.T) {
+	chain, w := chaintest.New(t)
+	tx := chaintest.SpendGenesis(t, chain, w, 70)
+
+	fees, err := chain.Fees([]*blockchain.Transaction{tx})
+	if err != nil || fees != 30 {
+		t.Fatalf("Fees = %d, %v, want 30", fees, err)
+	}
+
+	for _, c := range []struct {
+		fees  int
+		valid bool
+	}{
+		{0, true},
+		{30, true},
+		{31, false},
+	} {
+		coinbase := blockchain.CoinbaseTx(string(w.Address()), "", c.fees)
+		block := blockchain.CreateBlock([]*blockchain.Transaction{coinbase, tx}, chain.LastHash, 1)
+		if err := chain.VerifyBlock(block); (err == nil) != c.valid {
+			t.Errorf("coinbase claiming %d in fees: VerifyBlock = %v, want valid %v", c.fees, err, c.valid)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package blockchain
 
 import (
+	"blockchain/params"
 	"bytes"
 	"crypto/ecdsa"
 	"encoding/hex"
@@ -16,12 +17,14 @@ import (
 )
 
 const (
-	dbPath      = "./tmp/blocks_%s"
-	genesisData = "First Transaction from Genesis"
+	dbPath = "./tmp/blocks_%s"
 )
 
 var (
 	lastHashKey = []byte("lh")
+
+	// ErrUnknownParent 區塊的上一個區塊不在資料庫中, 無法驗證其中的交易
+	ErrUnknownParent = errors.New("parent block is unknown")
 )
 
 // BlockChain 區塊鏈
@@ -33,7 +36,7 @@ type BlockChain struct {
 // InitBlockChain 初始化 block chain
 func InitBlockChain(address, nodeID string) *BlockChain {
 	var lastHash []byte
-	path := fmt.Sprintf(dbPath, nodeID)
+	path := params.Active.Path(dbPath, nodeID)
 	if DBExists(path) {
 		fmt.Println("Blockchain already exists")
 		runtime.Goexit()
@@ -74,7 +77,7 @@ func InitBlockChain(address, nodeID string) *BlockChain {
 		// 	return err
 		// }
 
-		cbtx := CoinbaseTx(address, genesisData)
+		cbtx := CoinbaseTx(address, params.Active.GenesisData, 0)
 		genesis := Genesis(cbtx)
 		fmt.Println("Genesis created")
 		err := txn.Set(genesis.Hash, genesis.Serialize())
@@ -94,7 +97,7 @@ func InitBlockChain(address, nodeID string) *BlockChain {
 
 // ContinueBlockChain ...
 func ContinueBlockChain(nodeID string) *BlockChain {
-	path := fmt.Sprintf(dbPath, nodeID)
+	path := params.Active.Path(dbPath, nodeID)
 	fmt.Println(path)
 	if DBExists(path) == false {
 		fmt.Println("No existing blockchain found, create one!")
@@ -136,10 +139,11 @@ func (chain *BlockChain) MineBlock(txs []*Transaction) *Block {
 
 	fmt.Println("start to mine block")
 
-	for _, tx := range txs {
-		if chain.VerifyTransaction(tx) != true {
-			log.Panic().Msg("invalid transaction")
-		}
+	if chain.VerifyTransactions(txs) != true {
+		log.Panic().Msg("invalid transaction")
+	}
+	if TransactionsSize(txs) > MaxBlockSize {
+		log.Panic().Msg("transactions exceed MaxBlockSize")
 	}
 
 	err := chain.Database.View(func(txn *badger.Txn) error {
@@ -159,7 +163,8 @@ func (chain *BlockChain) MineBlock(txs []*Transaction) *Block {
 			lastBlockData = val
 			return nil
 		})
-		lastBlock := Deserialize(lastBlockData)
+		lastBlock, err := Deserialize(lastBlockData)
+		ErrHandler(err)
 		lastHeight = lastBlock.Height
 
 		return err
@@ -183,7 +188,7 @@ func (chain *BlockChain) MineBlock(txs []*Transaction) *Block {
 }
 func (chain *BlockChain) AddBlock(block *Block) {
 	err := chain.Database.Update(func(txn *badger.Txn) error {
-		if _, err := txn.Get(block.Hash); err != nil {
+		if _, err := txn.Get(block.Hash); err == nil {
 			return nil
 		}
 
@@ -211,7 +216,8 @@ func (chain *BlockChain) AddBlock(block *Block) {
 		})
 		ErrHandler(err)
 
-		lastBlock := Deserialize(lastBlockData)
+		lastBlock, err := Deserialize(lastBlockData)
+		ErrHandler(err)
 
 		if block.Height > lastBlock.Height {
 			err := txn.Set(lastHashKey, block.Hash)
@@ -240,7 +246,11 @@ func (chain *BlockChain) GetBlock(blockHash []byte) (Block, error) {
 				return nil
 			})
 
-			block = *Deserialize(blockData)
+			decoded, err := Deserialize(blockData)
+			if err != nil {
+				return err
+			}
+			block = *decoded
 		}
 		return nil
 	})
@@ -290,7 +300,9 @@ func (chain *BlockChain) GetBestHeight() int {
 			return nil
 		})
 
-		lastBlock = *Deserialize(lastBlockData)
+		decoded, err := Deserialize(lastBlockData)
+		ErrHandler(err)
+		lastBlock = *decoded
 
 		return nil
 	})
@@ -322,6 +334,9 @@ func (chain *BlockChain) FindUTXO() map[string]TXOutputs {
 
 		Outputs:
 			for outIDx, out := range tx.Outputs {
+				if out.IsData() {
+					continue
+				}
 				if spentTXOs[txID] != nil {
 					for _, spentOut := range spentTXOs[txID] {
 						if spentOut == outIDx {
@@ -330,7 +345,7 @@ func (chain *BlockChain) FindUTXO() map[string]TXOutputs {
 					}
 				}
 				outs := utxo[txID]
-				outs.Outputs = append(outs.Outputs, out)
+				outs.Add(outIDx, out)
 				utxo[txID] = outs
 			}
 			if tx.IsCoinbase() == false {
@@ -362,7 +377,12 @@ func (chain *BlockChain) SignTransaction(tx *Transaction, privKey ecdsa.PrivateK
 }
 
 func (chain *BlockChain) FindTransaction(id []byte) (Transaction, error) {
-	iter := chain.Iterator()
+	return chain.findTransactionFrom(chain.LastHash, id)
+}
+
+// findTransactionFrom 從 tip 往前尋找交易, tip 可以是分支上的區塊
+func (chain *BlockChain) findTransactionFrom(tip, id []byte) (Transaction, error) {
+	iter := &BlockChainIterator{tip, chain.Database}
 
 	for {
 		block := iter.Next()
@@ -380,20 +400,244 @@ func (chain *BlockChain) FindTransaction(id []byte) (Transaction, error) {
 	return Transaction{}, errors.New("Transaction is not exists")
 }
 
+// spentOutputsFrom 從 tip 到創世區塊之間已被花費的輸出, key 為交易 ID 的 hex
+func (chain *BlockChain) spentOutputsFrom(tip []byte) map[string][]int {
+	spent := make(map[string][]int)
+	iter := &BlockChainIterator{tip, chain.Database}
+
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transaction {
+			if tx.IsCoinbase() {
+				continue
+			}
+			for _, in := range tx.Inputs {
+				inTXID := hex.EncodeToString(in.ID)
+				spent[inTXID] = append(spent[inTXID], in.Out)
+			}
+		}
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+	return spent
+}
+
+// Fees 交易的手續費總和, 輸入可以花費同一批中排在前面的交易
+func (chain *BlockChain) Fees(txs []*Transaction) (int, error) {
+	fees := 0
+	batch := make(map[string]Transaction)
+
+	for _, tx := range txs {
+		if tx.IsCoinbase() {
+			continue
+		}
+		prevOuts, err := chain.PrevOutputsWith(tx, batch)
+		if err != nil {
+			return 0, err
+		}
+		fees += tx.Fee(prevOuts)
+		batch[hex.EncodeToString(tx.ID)] = *tx
+	}
+	return fees, nil
+}
+
+// FindData 找出包含指定資料輸出的區塊與交易
+func (chain *BlockChain) FindData(data []byte) (*Block, *Transaction, error) {
+	iter := chain.Iterator()
+
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transaction {
+			for _, out := range tx.Outputs {
+				if out.IsData() && bytes.Equal(out.Data, data) {
+					return block, tx, nil
+				}
+			}
+		}
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+	return nil, nil, errors.New("Data is not anchored")
+}
+
+// UsedPubKeyHashes 鏈上所有曾經收到輸出的公鑰雜湊 (hex), 供錢包還原時判斷地址是否使用過
+func (chain *BlockChain) UsedPubKeyHashes() map[string]bool {
+	used := make(map[string]bool)
+	iter := chain.Iterator()
+
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transaction {
+			for _, out := range tx.Outputs {
+				if !out.IsData() {
+					used[hex.EncodeToString(out.PubKeyHash)] = true
+				}
+			}
+		}
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+	return used
+}
+
+// PrevOutputs 從鏈上取出交易每個輸入所花費的輸出
+func (chain *BlockChain) PrevOutputs(tx *Transaction) ([]TxOutput, error) {
+	return chain.PrevOutputsWith(tx, nil)
+}
+
+// PrevOutputsWith 前一筆交易先從 unconfirmed 尋找, 找不到才查詢鏈上
+func (chain *BlockChain) PrevOutputsWith(tx *Transaction, unconfirmed map[string]Transaction) ([]TxOutput, error) {
+	var prevOuts []TxOutput
+
+	for _, in := range tx.Inputs {
+		prevTx, ok := unconfirmed[hex.EncodeToString(in.ID)]
+		if !ok {
+			var err error
+			prevTx, err = chain.FindTransaction(in.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if in.Out < 0 || in.Out >= len(prevTx.Outputs) {
+			return nil, errors.New("input references an unknown output")
+		}
+		prevOuts = append(prevOuts, prevTx.Outputs[in.Out])
+	}
+
+	return prevOuts, nil
+}
+
 func (chain *BlockChain) VerifyTransaction(tx *Transaction) bool {
-	if tx.IsCoinbase() {
-		return true
+	return chain.VerifyTransactions([]*Transaction{tx})
+}
+
+// VerifyTransactions 驗證一批交易 (例如整個區塊), 所有簽章交給 DefaultVerifier 平行驗證
+func (chain *BlockChain) VerifyTransactions(txs []*Transaction) bool {
+	return chain.VerifyTransactionsWith(txs, nil)
+}
+
+// VerifyTransactionsWith 輸入可以花費 unconfirmed 中的交易, 或同一批中排在前面的交易
+// unconfirmed 之間的衝突由 mempool 處理, 這裡只檢查輸出是否已在鏈上被花費
+func (chain *BlockChain) VerifyTransactionsWith(txs []*Transaction, unconfirmed map[string]Transaction) bool {
+	return verifyTransactions(txs, func(id []byte) (Transaction, error) {
+		if prevTx, ok := unconfirmed[hex.EncodeToString(id)]; ok {
+			return prevTx, nil
+		}
+		return chain.FindTransaction(id)
+	}, chain.spentOutputsFrom(chain.LastHash))
+}
+
+// VerifyBlock 驗證收到的區塊, 包括不在主鏈上的分支區塊: ValidateBlock 的檢查, 以及以區塊所在分支驗證交易與簽章.
+// 上一個區塊不在資料庫中時回傳 ErrUnknownParent
+func (chain *BlockChain) VerifyBlock(block *Block) error {
+	if err := ValidateBlock(block); err != nil {
+		return err
+	}
+
+	parent, err := chain.GetBlock(block.PrevHash)
+	if err != nil {
+		return ErrUnknownParent
+	}
+	if block.Height != parent.Height+1 {
+		return fmt.Errorf("block height %d does not follow its parent at %d", block.Height, parent.Height)
 	}
+
+	if !verifyTransactions(block.Transaction, func(id []byte) (Transaction, error) {
+		return chain.findTransactionFrom(block.PrevHash, id)
+	}, chain.spentOutputsFrom(block.PrevHash)) {
+		return errors.New("block contains invalid transactions")
+	}
+	return nil
+}
+
+// verifyTransactions find 取得輸入所引用的前一筆交易, spent 為 find 所在分支上已被花費的輸出.
+// 共識規則: 金額不可為負或超過 MaxMoney, 輸入不可重複花費, 輸入總額不少於輸出總額,
+// coinbase 最多領取 Subsidy 加上同一批交易的手續費
+func verifyTransactions(txs []*Transaction, find func(id []byte) (Transaction, error), spent map[string][]int) bool {
 	prevTXs := make(map[string]Transaction)
+	spentInBatch := make(map[string]bool)
+	fees, coinbaseValue := 0, 0
 
-	for _, in := range tx.Inputs {
-		prevTx, err := chain.FindTransaction(in.ID)
-		ErrHandler(err)
+	for _, tx := range txs {
+		// ID 必須是交易內容的雜湊, 否則輸入可以引用與內容不符的 ID
+		if !bytes.Equal(tx.ID, tx.Hash()) {
+			return false
+		}
+		outValue := 0
+		for _, out := range tx.Outputs {
+			if out.IsData() && (out.Value != 0 || len(out.Data) > MaxDataSize) {
+				return false
+			}
+			if out.Value < 0 || out.Value > MaxMoney {
+				return false
+			}
+			outValue += out.Value
+			if outValue > MaxMoney {
+				return false
+			}
+		}
+
+		if tx.IsCoinbase() {
+			coinbaseValue += outValue
+			continue
+		}
+		if len(tx.Inputs) == 0 {
+			return false
+		}
 
-		prevTXs[hex.EncodeToString(prevTx.ID)] = prevTx
+		inValue := 0
+		for _, in := range tx.Inputs {
+			key := hex.EncodeToString(in.ID)
+			prevTx, ok := prevTXs[key]
+			if !ok {
+				var err error
+				prevTx, err = find(in.ID)
+				if err != nil {
+					return false
+				}
+				prevTXs[key] = prevTx
+			}
+			if in.Out < 0 || in.Out >= len(prevTx.Outputs) || prevTx.Outputs[in.Out].IsData() {
+				return false
+			}
+
+			outpoint := fmt.Sprintf("%s:%d", key, in.Out)
+			if spentInBatch[outpoint] || isSpent(spent[key], in.Out) {
+				return false
+			}
+			spentInBatch[outpoint] = true
+			inValue += prevTx.Outputs[in.Out].Value
+		}
+		if inValue < outValue {
+			return false
+		}
+		fees += inValue - outValue
+
+		prevTXs[hex.EncodeToString(tx.ID)] = *tx
 	}
 
-	return tx.Verify(prevTXs)
+	if coinbaseValue > Subsidy+fees {
+		return false
+	}
+	return DefaultVerifier.VerifyTransactions(txs, prevTXs)
+}
+
+func isSpent(outs []int, out int) bool {
+	for _, spentOut := range outs {
+		if spentOut == out {
+			return true
+		}
+	}
+	return false
 }
 
 func retry(dir string, originalOpts badger.Options) (*badger.DB, error) {
@@ -416,7 +660,7 @@ func openDB(dir string, opts badger.Options) (*badger.DB, error) {
 				log.Debug().Msgf("database unlocked, value log truncated")
 				return db, nil
 			}
-			log.Debug().Msgf("could not unlock database:", err)
+			log.Debug().Msgf("could not unlock database: %s", err)
 		}
 		return nil, err
 	} else {
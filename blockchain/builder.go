@@ -0,0 +1,240 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Recipient 交易的一個收款對象
+type Recipient struct {
+	Address string
+	Amount  int
+}
+
+// ParseRecipient 解析 "address:amount" 格式
+func ParseRecipient(s string) (Recipient, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return Recipient{}, fmt.Errorf("recipient %q is not address:amount", s)
+	}
+
+	amount, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return Recipient{}, fmt.Errorf("recipient %q has invalid amount", s)
+	}
+
+	return Recipient{strings.TrimSpace(parts[0]), amount}, nil
+}
+
+// TxBuilder 組裝並簽署一般交易
+type TxBuilder struct {
+	Wallet        *wallet.Wallet
+	From          string // 只建立未簽署交易時的寄件地址, 空字串時使用 Wallet 的地址
+	UTXO          *UTXOSet
+	Recipients    []Recipient
+	ChangeAddress string                 // 空字串時找零回到寄件者地址
+	Data          []byte                 // 不為空時附加一個資料輸出
+	Selector      CoinSelector           // nil 時使用 LargestFirst
+	Inputs        []Outpoint             // 手動指定要花費的輸出, 指定時不使用 Selector
+	Fee           int                    // 支付給礦工的手續費
+	Replaceable   bool                   // 確認前可被提高手續費的交易取代
+	Unconfirmed   map[string]Transaction // 尚未確認的交易, 其輸出可被花費, 已被其花費的輸出不再選用
+}
+
+// Build 選擇寄件者的輸出, 建立收款與找零輸出並簽署
+func (b *TxBuilder) Build() (*Transaction, error) {
+	if b.Wallet == nil {
+		return nil, errors.New("signing requires a wallet")
+	}
+
+	p, err := b.BuildUnsigned()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Sign(b.Wallet, SigHashAll); err != nil {
+		return nil, err
+	}
+
+	return p.Finalize()
+}
+
+func (b *TxBuilder) from() string {
+	if b.From != "" {
+		return b.From
+	}
+	return string(b.Wallet.Address())
+}
+
+// BuildUnsigned 只需要寄件地址與 UTXO set, 產生可交給離線機器簽署的交易
+func (b *TxBuilder) BuildUnsigned() (*PartialTransaction, error) {
+	var (
+		inputs  []TxInput
+		outputs []TxOutput
+		amount  int
+	)
+
+	if len(b.Recipients) == 0 && len(b.Data) == 0 {
+		return nil, errors.New("transaction has no recipients")
+	}
+
+	for _, r := range b.Recipients {
+		if r.Amount <= 0 {
+			return nil, fmt.Errorf("amount for %s must be positive", r.Address)
+		}
+		if !wallet.ValidateAddress(r.Address) {
+			return nil, fmt.Errorf("address %s is not valid", r.Address)
+		}
+		if amount+r.Amount < amount {
+			return nil, errors.New("total amount overflows")
+		}
+		amount += r.Amount
+		outputs = append(outputs, *NewTXOutput(r.Amount, r.Address))
+	}
+
+	if len(b.Data) > 0 {
+		dataOut, err := NewDataOutput(b.Data)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, *dataOut)
+	}
+
+	from := b.from()
+	_, pubKeyHash, err := wallet.DecodeAddress(from)
+	if err != nil {
+		return nil, err
+	}
+
+	changeAddress := b.ChangeAddress
+	if changeAddress == "" {
+		changeAddress = from
+	}
+	if !wallet.ValidateAddress(changeAddress) {
+		return nil, fmt.Errorf("change address %s is not valid", changeAddress)
+	}
+
+	if b.Fee < 0 {
+		return nil, errors.New("fee must not be negative")
+	}
+	amount += b.Fee
+
+	// 只有資料輸出時仍需至少一個輸入
+	target := amount
+	if target == 0 {
+		target = 1
+	}
+
+	sequence := MaxSequence
+	if b.Replaceable {
+		sequence = ReplaceableSequence
+	}
+
+	coins, err := b.selectCoins(pubKeyHash, target)
+	if err != nil {
+		return nil, err
+	}
+
+	acc := 0
+	var prevOuts []TxOutput
+	for _, c := range coins {
+		acc += c.Output.Value
+		inputs = append(inputs, TxInput{c.TxID, c.Index, nil, nil, sequence})
+		prevOuts = append(prevOuts, c.Output)
+	}
+
+	// 低於 dust 的找零不會被節點轉送, 直接併入手續費
+	if acc-amount >= DefaultDustLimit {
+		outputs = append(outputs, *NewTXOutput(acc-amount, changeAddress))
+	}
+
+	tx := Transaction{nil, inputs, outputs}
+
+	return NewPartialTransaction(tx, prevOuts)
+}
+
+// selectCoins 取得手動指定的輸出, 或以 Selector 從寄件者的輸出中挑選
+func (b *TxBuilder) selectCoins(pubKeyHash []byte, target int) ([]Coin, error) {
+	if len(b.Inputs) == 0 {
+		selector := b.Selector
+		if selector == nil {
+			selector = LargestFirst{}
+		}
+		return selector.Select(b.availableCoins(pubKeyHash), target)
+	}
+
+	var coins []Coin
+	seen := make(map[string]bool)
+	for _, op := range b.Inputs {
+		if seen[op.String()] {
+			return nil, fmt.Errorf("output %s is listed twice", op)
+		}
+		seen[op.String()] = true
+
+		coin, err := b.findCoin(op)
+		if err != nil {
+			return nil, err
+		}
+		if !coin.Output.IsLockedWithKey(pubKeyHash) {
+			return nil, fmt.Errorf("output %s does not belong to the wallet", op)
+		}
+		coins = append(coins, coin)
+	}
+	if sumCoins(coins) < target {
+		return nil, ErrInsufficientFunds
+	}
+
+	return coins, nil
+}
+
+// unconfirmedSpent 尚未確認的交易已經花費的輸出
+func (b *TxBuilder) unconfirmedSpent() map[string]bool {
+	spent := make(map[string]bool)
+	for _, tx := range b.Unconfirmed {
+		for _, in := range tx.Inputs {
+			spent[Outpoint{in.ID, in.Out}.String()] = true
+		}
+	}
+	return spent
+}
+
+// availableCoins 鏈上與尚未確認的輸出中, 還沒有被尚未確認的交易花費的部分
+func (b *TxBuilder) availableCoins(pubKeyHash []byte) []Coin {
+	var coins []Coin
+	spent := b.unconfirmedSpent()
+
+	for _, c := range b.UTXO.FindCoins(pubKeyHash) {
+		if !spent[c.String()] {
+			coins = append(coins, c)
+		}
+	}
+
+	for _, tx := range b.Unconfirmed {
+		for index, out := range tx.Outputs {
+			op := Outpoint{tx.ID, index}
+			if !out.IsData() && out.IsLockedWithKey(pubKeyHash) && !spent[op.String()] {
+				coins = append(coins, Coin{op, out})
+			}
+		}
+	}
+
+	return coins
+}
+
+func (b *TxBuilder) findCoin(op Outpoint) (Coin, error) {
+	if b.unconfirmedSpent()[op.String()] {
+		return Coin{}, fmt.Errorf("output %s is spent by an unconfirmed transaction", op)
+	}
+
+	if tx, ok := b.Unconfirmed[hex.EncodeToString(op.TxID)]; ok {
+		if op.Index >= len(tx.Outputs) || tx.Outputs[op.Index].IsData() {
+			return Coin{}, fmt.Errorf("output %s is not spendable", op)
+		}
+		return Coin{op, tx.Outputs[op.Index]}, nil
+	}
+
+	return b.UTXO.FindCoin(op)
+}
@@ -26,7 +26,8 @@ func (iter *BlockChainIterator) Next() *Block {
 			encodeBlock = val
 			return nil
 		})
-		block = Deserialize(encodeBlock)
+		ErrHandler(err)
+		block, err = Deserialize(encodeBlock)
 
 		return err
 	})
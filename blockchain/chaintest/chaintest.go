@@ -0,0 +1,53 @@
+// Package chaintest 測試用的區塊鏈, 供各套件的測試共用
+package chaintest
+
+import (
+	"blockchain/blockchain"
+	"blockchain/wallet"
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+// New 在暫存目錄建立只有創世區塊的鏈, 創世獎勵付給回傳的錢包.
+// 資料檔案都以 ./tmp 為相對路徑, 因此測試期間工作目錄會切換到暫存目錄, 結束時關閉資料庫並切換回來
+func New(t testing.TB) (*blockchain.BlockChain, *wallet.Wallet) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(dir+"/tmp", 0700); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	w := wallet.MakeWallet(wallet.KeySecp256k1)
+	chain := blockchain.InitBlockChain(string(w.Address()), "test")
+	t.Cleanup(func() { chain.Database.Close() })
+	return chain, w
+}
+
+// SpendGenesis 花費創世獎勵, 付給 w 的輸出金額為 values
+func SpendGenesis(t testing.TB, chain *blockchain.BlockChain, w *wallet.Wallet, values ...int) *blockchain.Transaction {
+	t.Helper()
+
+	genesis, err := chain.GetBlock(chain.LastHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coinbase := genesis.Transaction[0]
+
+	tx := &blockchain.Transaction{Inputs: []blockchain.TxInput{{ID: coinbase.ID, Out: 0, PubKey: w.Publickey, Sequence: blockchain.MaxSequence}}}
+	for _, value := range values {
+		tx.Outputs = append(tx.Outputs, blockchain.TxOutput{Value: value, PubKeyHash: wallet.PublicKeyHash(w.Publickey), Type: w.Type})
+	}
+	tx.SetID()
+	tx.Sign(w.PrivateKey, map[string]blockchain.Transaction{hex.EncodeToString(coinbase.ID): *coinbase})
+	return tx
+}
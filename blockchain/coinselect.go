@@ -0,0 +1,218 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInsufficientFunds 可用的輸出不足以支付目標金額
+var ErrInsufficientFunds = errors.New("Error: not enough funds")
+
+// Outpoint 指向某筆交易的某個輸出
+type Outpoint struct {
+	TxID  []byte
+	Index int
+}
+
+// ParseOutpoint 解析 "txid:index" 格式
+func ParseOutpoint(s string) (Outpoint, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return Outpoint{}, fmt.Errorf("outpoint %q is not txid:index", s)
+	}
+
+	txID, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return Outpoint{}, fmt.Errorf("outpoint %q has invalid txid", s)
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 {
+		return Outpoint{}, fmt.Errorf("outpoint %q has invalid index", s)
+	}
+
+	return Outpoint{txID, index}, nil
+}
+
+func (op Outpoint) String() string {
+	return fmt.Sprintf("%x:%d", op.TxID, op.Index)
+}
+
+// Coin 一個可花費的輸出
+type Coin struct {
+	Outpoint
+	Output TxOutput
+}
+
+func sumCoins(coins []Coin) int {
+	total := 0
+	for _, c := range coins {
+		total += c.Output.Value
+	}
+	return total
+}
+
+// CoinSelector 從可用輸出中挑選足以支付 target 的組合
+type CoinSelector interface {
+	Select(coins []Coin, target int) ([]Coin, error)
+}
+
+// CoinSelectorByName 依名稱取得選擇策略, 供 send -coinSelect 使用
+func CoinSelectorByName(name string) (CoinSelector, error) {
+	switch name {
+	case "", "largest":
+		return LargestFirst{}, nil
+	case "smallest":
+		return SmallestFirst{}, nil
+	case "bnb":
+		return BranchAndBound{MaxTries: defaultBnBTries}, nil
+	case "random":
+		return RandomImprove{}, nil
+	}
+	return nil, fmt.Errorf("unknown coin selection %q", name)
+}
+
+// selectInOrder 依序加入輸出直到金額足夠
+func selectInOrder(coins []Coin, target int) ([]Coin, error) {
+	var (
+		selected []Coin
+		total    int
+	)
+
+	for _, c := range coins {
+		if total >= target {
+			break
+		}
+		selected = append(selected, c)
+		total += c.Output.Value
+	}
+	if total < target {
+		return nil, ErrInsufficientFunds
+	}
+
+	return selected, nil
+}
+
+// LargestFirst 優先使用金額最大的輸出, 輸入數最少
+type LargestFirst struct{}
+
+func (LargestFirst) Select(coins []Coin, target int) ([]Coin, error) {
+	sorted := append([]Coin{}, coins...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Output.Value > sorted[j].Output.Value
+	})
+
+	return selectInOrder(sorted, target)
+}
+
+// SmallestFirst 優先使用金額最小的輸出, 可逐步整合零碎的輸出
+type SmallestFirst struct{}
+
+func (SmallestFirst) Select(coins []Coin, target int) ([]Coin, error) {
+	sorted := append([]Coin{}, coins...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Output.Value < sorted[j].Output.Value
+	})
+
+	return selectInOrder(sorted, target)
+}
+
+const defaultBnBTries = 100000
+
+// BranchAndBound 搜尋總額剛好等於 target 的組合, 不產生找零; 找不到時回傳錯誤
+type BranchAndBound struct {
+	MaxTries int
+}
+
+func (b BranchAndBound) Select(coins []Coin, target int) ([]Coin, error) {
+	sorted := append([]Coin{}, coins...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Output.Value > sorted[j].Output.Value
+	})
+
+	// remaining[i] 為 sorted[i:] 的總額, 用於剪枝
+	remaining := make([]int, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].Output.Value
+	}
+	if remaining[0] < target {
+		return nil, ErrInsufficientFunds
+	}
+
+	var (
+		tries    int
+		selected []int
+		search   func(i, total int) bool
+	)
+
+	search = func(i, total int) bool {
+		tries++
+		if total == target {
+			return true
+		}
+		if i == len(sorted) || total > target || total+remaining[i] < target || tries > b.MaxTries {
+			return false
+		}
+
+		selected = append(selected, i)
+		if search(i+1, total+sorted[i].Output.Value) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+
+		return search(i+1, total)
+	}
+
+	if !search(0, 0) {
+		return nil, errors.New("no exact match without change")
+	}
+
+	result := make([]Coin, 0, len(selected))
+	for _, i := range selected {
+		result = append(result, sorted[i])
+	}
+	return result, nil
+}
+
+// RandomImprove 隨機挑選直到足夠, 再隨機加入輸出讓總額接近 target 的兩倍,
+// 使找零與付款金額相近以減少零碎輸出並提高隱私
+type RandomImprove struct{}
+
+func (RandomImprove) Select(coins []Coin, target int) ([]Coin, error) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	shuffled := append([]Coin{}, coins...)
+	rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	selected, err := selectInOrder(shuffled, target)
+	if err != nil {
+		return nil, err
+	}
+
+	total := sumCoins(selected)
+	ideal := 2 * target
+	for _, c := range shuffled[len(selected):] {
+		next := total + c.Output.Value
+		if next > 3*target || abs(ideal-next) >= abs(ideal-total) {
+			continue
+		}
+		selected = append(selected, c)
+		total = next
+	}
+
+	return selected, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
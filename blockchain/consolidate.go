@@ -0,0 +1,119 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Consolidator 把錢包地址上零碎的輸出合併成一個輸出, 減少之後付款所需的輸入數
+type Consolidator struct {
+	Wallet    *wallet.Wallet
+	UTXO      *UTXOSet
+	To        string // 空字串時合併回錢包地址
+	MaxInputs int    // 每筆交易最多的輸入數, 0 或超過 DefaultMaxSigOps 時使用 DefaultMaxSigOps
+	MinValue  int    // 金額低於此值的輸出不值得花費, 不會被合併
+	Fee       int    // 每筆交易的手續費
+}
+
+// candidates 由小到大排列可合併的輸出
+func (c *Consolidator) candidates() []Coin {
+	var coins []Coin
+	for _, coin := range c.UTXO.FindCoins(wallet.PublicKeyHash(c.Wallet.Publickey)) {
+		if coin.Output.Value >= c.MinValue {
+			coins = append(coins, coin)
+		}
+	}
+
+	sort.SliceStable(coins, func(i, j int) bool {
+		return coins[i].Output.Value < coins[j].Output.Value
+	})
+	return coins
+}
+
+func (c *Consolidator) maxInputs() int {
+	if c.MaxInputs <= 0 || c.MaxInputs > DefaultMaxSigOps {
+		return DefaultMaxSigOps
+	}
+	return c.MaxInputs
+}
+
+// build 花費所有 coins 建立單一輸出的交易, 超過大小上限時減少輸入數重試
+func (c *Consolidator) build(coins []Coin) (*Transaction, []Coin, error) {
+	to := c.To
+	if to == "" {
+		to = string(c.Wallet.Address())
+	}
+
+	for len(coins) > 0 {
+		value := sumCoins(coins) - c.Fee
+		if value < DefaultDustLimit {
+			return nil, nil, fmt.Errorf("consolidated value %d is below the dust limit after a fee of %d", value, c.Fee)
+		}
+
+		var outpoints []Outpoint
+		for _, coin := range coins {
+			outpoints = append(outpoints, coin.Outpoint)
+		}
+
+		builder := TxBuilder{
+			Wallet:     c.Wallet,
+			UTXO:       c.UTXO,
+			Recipients: []Recipient{{to, value}},
+			Inputs:     outpoints,
+			Fee:        c.Fee,
+		}
+		tx, err := builder.Build()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		size := tx.Size()
+		if size <= DefaultMaxTxSize {
+			return tx, coins, nil
+		}
+		coins = coins[:len(coins)*DefaultMaxTxSize/size]
+	}
+
+	return nil, nil, errors.New("no output fits within the transaction size limit")
+}
+
+// Build 由小到大合併最多 MaxInputs 個輸出, 至少需要兩個輸出
+func (c *Consolidator) Build() (*Transaction, error) {
+	coins := c.candidates()
+	if len(coins) > c.maxInputs() {
+		coins = coins[:c.maxInputs()]
+	}
+	if len(coins) < 2 {
+		return nil, fmt.Errorf("only %d output(s) of at least %d to consolidate", len(coins), c.MinValue)
+	}
+
+	tx, _, err := c.build(coins)
+	return tx, err
+}
+
+// Sweep 花費所有可合併的輸出, 超過單筆交易的限制時分成多筆交易
+func (c *Consolidator) Sweep() ([]*Transaction, error) {
+	coins := c.candidates()
+	if len(coins) == 0 {
+		return nil, ErrInsufficientFunds
+	}
+
+	var txs []*Transaction
+	for len(coins) > 0 {
+		batch := coins
+		if len(batch) > c.maxInputs() {
+			batch = batch[:c.maxInputs()]
+		}
+
+		tx, used, err := c.build(batch)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+		coins = coins[len(used):]
+	}
+
+	return txs, nil
+}
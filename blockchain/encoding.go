@@ -0,0 +1,320 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// 序列化格式版本, 放在每筆編碼的第一個位元組
+const (
+	txEncodingVersion      = byte(0x03)
+	blockEncodingVersion   = byte(0x03)
+	outputsEncodingVersion = byte(0x02)
+
+	// maxFieldLength 單一長度前綴欄位的上限, 避免惡意資料造成大量配置
+	maxFieldLength = 32 * 1024 * 1024
+)
+
+var errFieldTooLong = errors.New("encoded field is too long")
+
+// encoder 以固定格式寫入: 整數使用 varint, 時間戳與 sequence 使用 big endian, 位元組欄位帶長度前綴
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) writeByte(b byte) {
+	e.buf.WriteByte(b)
+}
+
+func (e *encoder) writeUvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	e.buf.Write(tmp[:n])
+}
+
+func (e *encoder) writeVarint(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	e.buf.Write(tmp[:n])
+}
+
+func (e *encoder) writeUint64(v uint64) {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], v)
+	e.buf.Write(tmp[:])
+}
+
+func (e *encoder) writeUint32(v uint32) {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	e.buf.Write(tmp[:])
+}
+
+func (e *encoder) writeBytes(b []byte) {
+	e.writeUvarint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+func (e *encoder) Bytes() []byte {
+	return e.buf.Bytes()
+}
+
+// decoder 讀取 encoder 產生的資料, 第一個錯誤之後的讀取都會被忽略
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func newDecoder(data []byte) *decoder {
+	return &decoder{r: bytes.NewReader(data)}
+}
+
+func (d *decoder) readByte() byte {
+	if d.err != nil {
+		return 0
+	}
+	b, err := d.r.ReadByte()
+	d.err = err
+
+	return b
+}
+
+func (d *decoder) readUvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	d.err = err
+
+	return v
+}
+
+func (d *decoder) readVarint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	d.err = err
+
+	return v
+}
+
+func (d *decoder) readInt() int {
+	v := d.readVarint()
+	if int64(int(v)) != v {
+		d.fail(errors.New("encoded integer overflows int"))
+		return 0
+	}
+
+	return int(v)
+}
+
+func (d *decoder) readUint64() uint64 {
+	var tmp [8]byte
+	if d.err != nil {
+		return 0
+	}
+	_, err := io.ReadFull(d.r, tmp[:])
+	d.err = err
+
+	return binary.BigEndian.Uint64(tmp[:])
+}
+
+func (d *decoder) readUint32() uint32 {
+	var tmp [4]byte
+	if d.err != nil {
+		return 0
+	}
+	_, err := io.ReadFull(d.r, tmp[:])
+	d.err = err
+
+	return binary.BigEndian.Uint32(tmp[:])
+}
+
+// readCount 讀取元素數量, 每個元素至少佔用 minSize 個位元組
+func (d *decoder) readCount(minSize int) int {
+	n := d.readUvarint()
+	if d.err == nil && n > uint64(d.r.Len()/minSize) {
+		d.fail(errFieldTooLong)
+		return 0
+	}
+
+	return int(n)
+}
+
+func (d *decoder) readBytes() []byte {
+	n := d.readUvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > maxFieldLength || n > uint64(d.r.Len()) {
+		d.fail(errFieldTooLong)
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+
+	b := make([]byte, n)
+	_, err := io.ReadFull(d.r, b)
+	d.err = err
+
+	return b
+}
+
+func (d *decoder) readVersion(expected byte) {
+	if v := d.readByte(); d.err == nil && v != expected {
+		d.fail(errors.New("unsupported encoding version"))
+	}
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// finish 確認資料已完整讀取, 不允許多餘的位元組
+func (d *decoder) finish() error {
+	if d.err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if d.err == nil && d.r.Len() != 0 {
+		return errors.New("trailing bytes after encoded data")
+	}
+
+	return d.err
+}
+
+func encodeOutput(e *encoder, out *TxOutput) {
+	e.writeVarint(int64(out.Value))
+	e.writeBytes(out.PubKeyHash)
+	e.writeBytes(out.Data)
+	e.writeByte(byte(out.Type))
+}
+
+func decodeOutput(d *decoder) TxOutput {
+	var out TxOutput
+
+	out.Value = d.readInt()
+	out.PubKeyHash = d.readBytes()
+	out.Data = d.readBytes()
+	out.Type = wallet.KeyType(d.readByte())
+
+	return out
+}
+
+func encodeTransaction(e *encoder, tx *Transaction) {
+	e.writeByte(txEncodingVersion)
+	e.writeBytes(tx.ID)
+
+	e.writeUvarint(uint64(len(tx.Inputs)))
+	for _, in := range tx.Inputs {
+		e.writeBytes(in.ID)
+		e.writeVarint(int64(in.Out))
+		e.writeBytes(in.Signature)
+		e.writeBytes(in.PubKey)
+		e.writeUint32(in.Sequence)
+	}
+
+	e.writeUvarint(uint64(len(tx.Outputs)))
+	for i := range tx.Outputs {
+		encodeOutput(e, &tx.Outputs[i])
+	}
+}
+
+func decodeTransaction(d *decoder) Transaction {
+	var tx Transaction
+
+	d.readVersion(txEncodingVersion)
+	tx.ID = d.readBytes()
+
+	inputs := d.readCount(8)
+	for i := 0; i < inputs && d.err == nil; i++ {
+		var in TxInput
+		in.ID = d.readBytes()
+		in.Out = d.readInt()
+		in.Signature = d.readBytes()
+		in.PubKey = d.readBytes()
+		in.Sequence = d.readUint32()
+		tx.Inputs = append(tx.Inputs, in)
+	}
+
+	outputs := d.readCount(4)
+	for i := 0; i < outputs && d.err == nil; i++ {
+		tx.Outputs = append(tx.Outputs, decodeOutput(d))
+	}
+
+	return tx
+}
+
+// DecodeTransaction 解析 Transaction.Serialize 的輸出
+func DecodeTransaction(data []byte) (Transaction, error) {
+	d := newDecoder(data)
+	tx := decodeTransaction(d)
+	if err := d.finish(); err != nil {
+		return tx, err
+	}
+
+	return tx, checkCanonical(data, tx.Serialize())
+}
+
+// DecodeBlock 解析 Block.Serialize 的輸出
+func DecodeBlock(data []byte) (*Block, error) {
+	var block Block
+
+	d := newDecoder(data)
+	d.readVersion(blockEncodingVersion)
+	block.Timestamp = d.readUint64()
+	block.Hash = d.readBytes()
+
+	txs := d.readCount(1)
+	for i := 0; i < txs && d.err == nil; i++ {
+		tx, err := DecodeTransaction(d.readBytes())
+		d.fail(err)
+		block.Transaction = append(block.Transaction, &tx)
+	}
+
+	block.PrevHash = d.readBytes()
+	block.Nonce = d.readInt()
+	block.Height = d.readInt()
+	if err := d.finish(); err != nil {
+		return nil, err
+	}
+	if err := checkCanonical(data, block.Serialize()); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// DecodeOutputs 解析 TXOutputs.Serialize 的輸出
+func DecodeOutputs(data []byte) (TXOutputs, error) {
+	var outputs TXOutputs
+
+	d := newDecoder(data)
+	d.readVersion(outputsEncodingVersion)
+
+	n := d.readCount(4)
+	for i := 0; i < n && d.err == nil; i++ {
+		index := d.readInt()
+		outputs.Add(index, decodeOutput(d))
+	}
+	if err := d.finish(); err != nil {
+		return outputs, err
+	}
+
+	return outputs, checkCanonical(data, outputs.Serialize())
+}
+
+// checkCanonical 拒絕可以被解析但與重新編碼結果不同的資料 (例如非最短的 varint)
+func checkCanonical(data, reencoded []byte) error {
+	if !bytes.Equal(data, reencoded) {
+		return errors.New("non-canonical encoding")
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// encodingTx 所有欄位都不為空的交易; 空的位元組欄位解碼後為 nil, 不適合用於比較
+func encodingTx() Transaction {
+	tx := Transaction{
+		Inputs: []TxInput{
+			{ID: bytes.Repeat([]byte{0x01}, 32), Out: 0, Signature: bytes.Repeat([]byte{0x02}, SignatureLength+1), PubKey: bytes.Repeat([]byte{0x03}, 33), Sequence: MaxSequence},
+			{ID: bytes.Repeat([]byte{0x04}, 32), Out: 300, Signature: []byte{0x05}, PubKey: []byte{0x06}, Sequence: ReplaceableSequence},
+		},
+		Outputs: []TxOutput{
+			{Value: 100, PubKeyHash: bytes.Repeat([]byte{0x07}, 20), Data: []byte{0x08}, Type: wallet.KeySecp256k1},
+			{Value: -1, PubKeyHash: []byte{0x09}, Data: []byte("anchor"), Type: wallet.KeySchnorr},
+		},
+	}
+	tx.SetID()
+	return tx
+}
+
+func encodingBlock() *Block {
+	tx := encodingTx()
+	return &Block{
+		Timestamp:   1700000000,
+		Hash:        bytes.Repeat([]byte{0x0a}, 32),
+		Transaction: []*Transaction{&tx, &tx},
+		PrevHash:    bytes.Repeat([]byte{0x0b}, 32),
+		Nonce:       123456,
+		Height:      42,
+	}
+}
+
+func TestTransactionRoundTrip(t *testing.T) {
+	tx := encodingTx()
+	data := tx.Serialize()
+
+	decoded, err := DecodeTransaction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, tx) {
+		t.Fatalf("decoded %+v, want %+v", decoded, tx)
+	}
+	if !bytes.Equal(decoded.Serialize(), data) {
+		t.Fatal("re-encoding changed the bytes")
+	}
+}
+
+func TestTransactionRoundTripEmptyFields(t *testing.T) {
+	tx := *CoinbaseTx(string(wallet.MakeWallet(wallet.KeyP256).Address()), "genesis", 0)
+	data := tx.Serialize()
+
+	decoded, err := DecodeTransaction(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Serialize(), data) || !bytes.Equal(decoded.Hash(), tx.ID) {
+		t.Fatal("coinbase does not survive a round trip")
+	}
+	if !decoded.IsCoinbase() {
+		t.Fatal("decoded coinbase is not a coinbase")
+	}
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	block := encodingBlock()
+	data := block.Serialize()
+
+	decoded, err := DecodeBlock(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, block) {
+		t.Fatalf("decoded %+v, want %+v", decoded, block)
+	}
+}
+
+func TestOutputsRoundTrip(t *testing.T) {
+	var outputs TXOutputs
+	for i, out := range encodingTx().Outputs {
+		outputs.Add(i*3, out)
+	}
+	data := outputs.Serialize()
+
+	decoded, err := DecodeOutputs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, outputs) {
+		t.Fatalf("decoded %+v, want %+v", decoded, outputs)
+	}
+}
+
+// overlongUvarint 以多一個位元組編碼 v, 值相同但不是最短的形式
+func overlongUvarint(v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	tmp[n-1] |= 0x80
+	return append(tmp[:n], 0x00)
+}
+
+func TestDecodeRejectsNonCanonicalVarint(t *testing.T) {
+	tx := encodingTx()
+	data := tx.Serialize()
+
+	// 版本之後是 ID 的長度前綴
+	prefixLen := len(overlongUvarint(uint64(len(tx.ID)))) - 1
+	overlong := append([]byte{data[0]}, overlongUvarint(uint64(len(tx.ID)))...)
+	overlong = append(overlong, data[1+prefixLen:]...)
+
+	d := newDecoder(overlong)
+	if parsed := decodeTransaction(d); d.finish() != nil || !reflect.DeepEqual(parsed, tx) {
+		t.Fatal("overlong varint should still parse to the same transaction")
+	}
+	if _, err := DecodeTransaction(overlong); err == nil {
+		t.Fatal("accepted an overlong varint")
+	}
+
+	outputs := TXOutputs{}
+	outputs.Add(1, tx.Outputs[0])
+	data = outputs.Serialize()
+	overlong = append([]byte{data[0]}, overlongUvarint(1)...)
+	overlong = append(overlong, data[2:]...)
+	if _, err := DecodeOutputs(overlong); err == nil {
+		t.Fatal("accepted an overlong output count")
+	}
+}
+
+func TestDecodeRejectsTrailingBytes(t *testing.T) {
+	tx := encodingTx()
+	if _, err := DecodeTransaction(append(tx.Serialize(), 0x00)); err == nil {
+		t.Error("transaction: accepted trailing bytes")
+	}
+	if _, err := DecodeBlock(append(encodingBlock().Serialize(), 0x00)); err == nil {
+		t.Error("block: accepted trailing bytes")
+	}
+	outputs := TXOutputs{}
+	outputs.Add(0, tx.Outputs[0])
+	if _, err := DecodeOutputs(append(outputs.Serialize(), 0x00)); err == nil {
+		t.Error("outputs: accepted trailing bytes")
+	}
+}
+
+func TestDecodeRejectsUnknownVersion(t *testing.T) {
+	tx := encodingTx()
+	outputs := TXOutputs{}
+	outputs.Add(0, tx.Outputs[0])
+
+	cases := []struct {
+		name   string
+		data   []byte
+		decode func([]byte) error
+	}{
+		{"transaction", tx.Serialize(), func(b []byte) error { _, err := DecodeTransaction(b); return err }},
+		{"block", encodingBlock().Serialize(), func(b []byte) error { _, err := DecodeBlock(b); return err }},
+		{"outputs", outputs.Serialize(), func(b []byte) error { _, err := DecodeOutputs(b); return err }},
+	}
+	for _, c := range cases {
+		for _, version := range []byte{0x00, c.data[0] - 1, c.data[0] + 1, 0xff} {
+			data := append([]byte{version}, c.data[1:]...)
+			if err := c.decode(data); err == nil {
+				t.Errorf("%s: accepted version %#x", c.name, version)
+			}
+		}
+	}
+}
+
+func TestDecodeRejectsTruncated(t *testing.T) {
+	data := encodingTx().Serialize()
+	for n := 0; n < len(data); n++ {
+		if _, err := DecodeTransaction(data[:n]); err == nil {
+			t.Fatalf("accepted a transaction truncated to %d bytes", n)
+		}
+	}
+}
+
+func TestDecodeRejectsOversizedLength(t *testing.T) {
+	var e encoder
+	e.writeByte(txEncodingVersion)
+	e.writeUvarint(maxFieldLength + 1)
+	if _, err := DecodeTransaction(e.Bytes()); err == nil {
+		t.Error("accepted a field longer than maxFieldLength")
+	}
+
+	e = encoder{}
+	e.writeByte(txEncodingVersion)
+	e.writeBytes(nil)
+	e.writeUvarint(1 << 40)
+	if _, err := DecodeTransaction(e.Bytes()); err == nil {
+		t.Error("accepted an input count larger than the data")
+	}
+}
+
+// FuzzDecodeTransaction 任意輸入都不能 panic, 成功解析的資料重新編碼後必須完全相同
+func FuzzDecodeTransaction(f *testing.F) {
+	tx := encodingTx()
+	f.Add(tx.Serialize())
+	f.Add(CoinbaseTx(string(wallet.MakeWallet(wallet.KeyP256).Address()), "seed", 0).Serialize())
+	f.Add([]byte{txEncodingVersion})
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded, err := DecodeTransaction(data)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(decoded.Serialize(), data) {
+			t.Fatalf("decoded %x re-encodes to %x", data, decoded.Serialize())
+		}
+	})
+}
+
+func TestDeserializeReturnsNoPartialBlock(t *testing.T) {
+	data := encodingBlock().Serialize()
+	block, err := Deserialize(data[:len(data)-1])
+	if err == nil || block != nil {
+		t.Fatalf("truncated block decoded to %v, %v", block, err)
+	}
+}
@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"bytes"
+	"encoding/hex"
+)
+
+// blocksAfter 主鏈上高度大於 height 的區塊, 由新到舊; tip 不為空且不是主鏈上 height 的區塊時回傳 false
+func (chain *BlockChain) blocksAfter(height int, tip []byte) ([]*Block, bool) {
+	var blocks []*Block
+	iter := chain.Iterator()
+
+	for {
+		block := iter.Next()
+		if block.Height <= height {
+			return blocks, tip == nil || bytes.Equal(block.Hash, tip)
+		}
+		blocks = append(blocks, block)
+
+		if len(block.PrevHash) == 0 {
+			return blocks, height < 0
+		}
+	}
+}
+
+// SyncHistory 把錢包交易紀錄更新到目前的鏈頂: 連接新的區塊; 紀錄的鏈頂已不在主鏈上時 (切換到其他分支)
+// 中斷連接所有區塊並重新掃描. unconfirmed 中與錢包有關的交易記錄為未確認.
+// 節點接收區塊時不會更新錢包紀錄, 紀錄是在讀取它的命令執行時才同步;
+// 新加入的地址不會出現在已掃描的區塊中, 加入地址的命令須改用 RescanHistory
+func (chain *BlockChain) SyncHistory(h *wallet.History, ws *wallet.Wallets, unconfirmed map[string]Transaction) {
+	owned := ws.KeyHashes()
+
+	blocks, ok := chain.blocksAfter(h.Height, h.Tip)
+	if !ok {
+		h.Rewind(0)
+		blocks, _ = chain.blocksAfter(h.Height, nil)
+	}
+
+	h.DropUnconfirmed()
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		for _, tx := range block.Transaction {
+			chain.recordTx(h, ws, owned, tx, block, unconfirmed)
+		}
+		h.Tip = block.Hash
+		h.Height = block.Height
+	}
+
+	for _, tx := range unconfirmed {
+		tx := tx
+		chain.recordTx(h, ws, owned, &tx, nil, unconfirmed)
+	}
+}
+
+// RescanHistory 從 height 開始重新掃描, 例如匯入金鑰之後
+func (chain *BlockChain) RescanHistory(h *wallet.History, ws *wallet.Wallets, height int, unconfirmed map[string]Transaction) {
+	h.Rewind(height)
+	chain.SyncHistory(h, ws, unconfirmed)
+}
+
+// recordTx 記錄花費或付給錢包地址的交易, block 為 nil 時為未確認
+func (chain *BlockChain) recordTx(h *wallet.History, ws *wallet.Wallets, owned map[string]string, tx *Transaction, block *Block, unconfirmed map[string]Transaction) {
+	t := &wallet.HistoryTx{ID: tx.ID, Height: -1, Coinbase: tx.IsCoinbase(), WatchOnly: true}
+	if block != nil {
+		t.BlockHash = block.Hash
+		t.Height = block.Height
+		t.Timestamp = block.Timestamp
+	}
+
+	var (
+		spends [][]byte
+		outs   []int
+	)
+	if !t.Coinbase {
+		for _, in := range tx.Inputs {
+			if address, ok := owned[hex.EncodeToString(wallet.PublicKeyHash(in.PubKey))]; ok {
+				spends = append(spends, in.ID)
+				outs = append(outs, in.Out)
+				t.WatchOnly = t.WatchOnly && ws.IsWatched(address)
+			}
+		}
+	}
+
+	for i, out := range tx.Outputs {
+		if out.IsData() {
+			continue
+		}
+		address, ok := owned[hex.EncodeToString(out.PubKeyHash)]
+		if !ok {
+			continue
+		}
+		t.Outputs = append(t.Outputs, wallet.HistoryOutput{
+			Index:   i,
+			Address: address,
+			Value:   out.Value,
+			Change:  len(spends) > 0,
+		})
+		t.WatchOnly = t.WatchOnly && ws.IsWatched(address)
+	}
+
+	if len(spends) == 0 && len(t.Outputs) == 0 {
+		return
+	}
+
+	// 錢包付款的交易由前一筆輸出計算花費金額與手續費
+	if len(spends) > 0 {
+		prevOuts, err := chain.PrevOutputsWith(tx, unconfirmed)
+		ErrHandler(err)
+		fee := 0
+		for i, prevOut := range prevOuts {
+			fee += prevOut.Value
+			if _, ok := owned[hex.EncodeToString(wallet.PublicKeyHash(tx.Inputs[i].PubKey))]; ok {
+				t.Sent += prevOut.Value
+			}
+		}
+		for _, out := range tx.Outputs {
+			fee -= out.Value
+		}
+		t.Fee = fee
+	}
+
+	h.Add(t, spends, outs)
+}
@@ -0,0 +1,74 @@
+package blockchain
+
+import (
+	"blockchain/params"
+	"blockchain/wallet"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// messageMagic 訊息簽章的網域分隔前綴, 簽過的訊息不可能同時是交易的簽章摘要
+const messageMagic = "Blockchain Signed Message:\n"
+
+// MessageDigest 訊息摘要: 前綴, 網路名稱與訊息皆加上長度後做兩次 SHA-256,
+// 不同網路的簽章互不通用
+func MessageDigest(message string) []byte {
+	var buf bytes.Buffer
+	for _, part := range []string{messageMagic, params.Active.Name, message} {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(part)))
+		buf.Write(length[:])
+		buf.WriteString(part)
+	}
+
+	first := sha256.Sum256(buf.Bytes())
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+// SignMessage 以錢包的私鑰簽署訊息, 回傳 base64 編碼的 公鑰長度 + 公鑰 + 簽章
+func SignMessage(w *wallet.Wallet, message string) (string, error) {
+	if w.IsWatchOnly() {
+		return "", wallet.ErrWatchOnly
+	}
+
+	sig, err := signDigest(w.Type, &w.PrivateKey, MessageDigest(message))
+	if err != nil {
+		return "", err
+	}
+
+	blob := append([]byte{byte(len(w.Publickey))}, w.Publickey...)
+	return base64.StdEncoding.EncodeToString(append(blob, sig...)), nil
+}
+
+// VerifyMessage 驗證簽章, 並確認簽章中的公鑰雜湊與地址相符
+func VerifyMessage(address, signature, message string) error {
+	keyType, pubKeyHash, err := wallet.DecodeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.New("signature is not base64")
+	}
+	if len(blob) < 1 || len(blob) != 1+int(blob[0])+SignatureLength {
+		return errors.New("signature has wrong length")
+	}
+	pubKey := blob[1 : 1+int(blob[0])]
+	sig := blob[1+int(blob[0]):]
+
+	if err := wallet.ValidatePublicKey(keyType, pubKey); err != nil {
+		return err
+	}
+	if !bytes.Equal(wallet.PublicKeyHash(pubKey), pubKeyHash) {
+		return errors.New("signature was made by a different address")
+	}
+	if !verifySignature(keyType, pubKey, MessageDigest(message), sig) {
+		return errors.New("signature does not match the message")
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+const partialEncodingVersion = byte(0x01)
+
+// PartialTransaction 部分簽署的交易, 讓建立, 簽署與廣播可以在不同機器上進行
+// Tx 不含簽章與公鑰, PrevOuts 為每個輸入所花費的輸出, 離線簽署時不需要區塊鏈
+type PartialTransaction struct {
+	Tx         Transaction
+	PrevOuts   []TxOutput
+	PubKeys    [][]byte
+	Signatures [][]byte
+}
+
+// NewPartialTransaction ...
+func NewPartialTransaction(tx Transaction, prevOuts []TxOutput) (*PartialTransaction, error) {
+	if len(prevOuts) != len(tx.Inputs) {
+		return nil, errors.New("previous outputs do not match inputs")
+	}
+
+	unsigned := tx.TrimmedCopy()
+	unsigned.ID = unsigned.Hash()
+
+	return &PartialTransaction{
+		Tx:         unsigned,
+		PrevOuts:   prevOuts,
+		PubKeys:    make([][]byte, len(tx.Inputs)),
+		Signatures: make([][]byte, len(tx.Inputs)),
+	}, nil
+}
+
+// Sign 以錢包簽署所有屬於它的輸入, 回傳簽署的輸入數量
+func (p *PartialTransaction) Sign(w *wallet.Wallet, hashType byte) (int, error) {
+	signed := 0
+	pubKeyHash := wallet.PublicKeyHash(w.Publickey)
+
+	for inID, prevOut := range p.PrevOuts {
+		if !prevOut.IsLockedWithKey(pubKeyHash) {
+			continue
+		}
+
+		txCopy := p.Tx.TrimmedCopy()
+		if err := txCopy.SignInput(inID, w.PrivateKey, prevOut, hashType); err != nil {
+			return signed, err
+		}
+
+		p.PubKeys[inID] = w.Publickey
+		p.Signatures[inID] = txCopy.Inputs[inID].Signature
+		signed++
+	}
+
+	return signed, nil
+}
+
+// Combine 合併另一份相同交易收集到的簽章, 交易 (含輸入) 與每個輸入花費的輸出都必須相同
+func (p *PartialTransaction) Combine(other *PartialTransaction) error {
+	if !bytes.Equal(p.Tx.Serialize(), other.Tx.Serialize()) {
+		return errors.New("partial transactions are for different transactions")
+	}
+	if len(p.PrevOuts) != len(other.PrevOuts) || len(p.Signatures) != len(other.Signatures) {
+		return errors.New("partial transactions have different numbers of inputs")
+	}
+	for inID := range p.PrevOuts {
+		if !samePrevOut(&p.PrevOuts[inID], &other.PrevOuts[inID]) {
+			return fmt.Errorf("partial transactions disagree on the output spent by input %d", inID)
+		}
+	}
+
+	for inID := range p.Signatures {
+		if len(p.Signatures[inID]) == 0 && len(other.Signatures[inID]) > 0 {
+			p.PubKeys[inID] = other.PubKeys[inID]
+			p.Signatures[inID] = other.Signatures[inID]
+		}
+	}
+
+	return nil
+}
+
+func samePrevOut(a, b *TxOutput) bool {
+	return a.Value == b.Value && a.Type == b.Type && bytes.Equal(a.PubKeyHash, b.PubKeyHash) && bytes.Equal(a.Data, b.Data)
+}
+
+// IsComplete 每個輸入都已有簽章
+func (p *PartialTransaction) IsComplete() bool {
+	for _, sig := range p.Signatures {
+		if len(sig) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Finalize 將簽章放入交易並驗證, 回傳可以廣播的交易
+func (p *PartialTransaction) Finalize() (*Transaction, error) {
+	if !p.IsComplete() {
+		return nil, errors.New("partial transaction is missing signatures")
+	}
+
+	tx := p.Tx.TrimmedCopy()
+	for inID := range tx.Inputs {
+		tx.Inputs[inID].PubKey = p.PubKeys[inID]
+	}
+	tx.ID = tx.Hash()
+
+	for inID := range tx.Inputs {
+		tx.Inputs[inID].Signature = p.Signatures[inID]
+	}
+
+	if !tx.VerifyWithPrevOuts(p.PrevOuts) {
+		return nil, errors.New("partial transaction has invalid signatures")
+	}
+
+	return &tx, nil
+}
+
+// Serialize ...
+func (p *PartialTransaction) Serialize() []byte {
+	var e encoder
+
+	e.writeByte(partialEncodingVersion)
+	e.writeBytes(p.Tx.Serialize())
+	e.writeUvarint(uint64(len(p.PrevOuts)))
+	for inID := range p.PrevOuts {
+		encodeOutput(&e, &p.PrevOuts[inID])
+		e.writeBytes(p.PubKeys[inID])
+		e.writeBytes(p.Signatures[inID])
+	}
+
+	return e.Bytes()
+}
+
+// DecodePartialTransaction 解析 PartialTransaction.Serialize 的輸出
+func DecodePartialTransaction(data []byte) (*PartialTransaction, error) {
+	var p PartialTransaction
+
+	d := newDecoder(data)
+	d.readVersion(partialEncodingVersion)
+
+	tx, err := DecodeTransaction(d.readBytes())
+	d.fail(err)
+	p.Tx = tx
+
+	n := d.readCount(6)
+	for i := 0; i < n && d.err == nil; i++ {
+		p.PrevOuts = append(p.PrevOuts, decodeOutput(d))
+		p.PubKeys = append(p.PubKeys, d.readBytes())
+		p.Signatures = append(p.Signatures, d.readBytes())
+	}
+	if err := d.finish(); err != nil {
+		return nil, err
+	}
+	if len(p.PrevOuts) != len(p.Tx.Inputs) {
+		return nil, fmt.Errorf("partial transaction has %d previous outputs for %d inputs", len(p.PrevOuts), len(p.Tx.Inputs))
+	}
+
+	return &p, nil
+}
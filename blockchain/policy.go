@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 預設的 standardness 規則, 節點可以用 startNode 參數調整
+const (
+	DefaultDustLimit           = 5
+	DefaultMaxTxSize           = 100000
+	DefaultMaxSigOps           = 500
+	DefaultMaxDataOutputs      = 1
+	DefaultIncrementalRelayFee = 1
+
+	pubKeyHashLength = 20 // ripemd160
+)
+
+// Policy 節點轉送與放入 mempool 的規則 (standardness), 與共識規則分開:
+// 不符合的交易不會被轉送, 但包含在合法區塊中時仍然有效
+type Policy struct {
+	DustLimit           int // 金額低於此值的輸出視為 dust
+	MaxTxSize           int // 序列化後的最大位元組數
+	MaxSigOps           int // 單一交易最多的簽章驗證次數
+	MaxDataOutputs      int // 單一交易最多的資料輸出數
+	IncrementalRelayFee int // 取代交易的手續費至少要比被移除的交易 (含子孫) 總和多出此值
+}
+
+// DefaultPolicy ...
+func DefaultPolicy() Policy {
+	return Policy{DefaultDustLimit, DefaultMaxTxSize, DefaultMaxSigOps, DefaultMaxDataOutputs, DefaultIncrementalRelayFee}
+}
+
+// SigOps 驗證交易需要檢查的簽章數量, 每個輸入一個
+func (tx *Transaction) SigOps() int {
+	if tx.IsCoinbase() {
+		return 0
+	}
+	return len(tx.Inputs)
+}
+
+// CheckStandard 回傳交易不符合的第一條規則
+func (p Policy) CheckStandard(tx *Transaction) error {
+	if tx.IsCoinbase() {
+		return errors.New("coinbase transaction is not standard outside a block")
+	}
+
+	if size := tx.Size(); size > p.MaxTxSize {
+		return fmt.Errorf("transaction size %d exceeds %d bytes", size, p.MaxTxSize)
+	}
+	if sigOps := tx.SigOps(); sigOps > p.MaxSigOps {
+		return fmt.Errorf("transaction has %d signature operations, limit is %d", sigOps, p.MaxSigOps)
+	}
+
+	dataOutputs := 0
+	for i, out := range tx.Outputs {
+		if out.IsData() {
+			dataOutputs++
+			continue
+		}
+		if !out.Type.Valid() || len(out.PubKeyHash) != pubKeyHashLength {
+			return fmt.Errorf("output %d has an unknown type", i)
+		}
+		if out.Value < p.DustLimit {
+			return fmt.Errorf("output %d value %d is below the dust limit %d", i, out.Value, p.DustLimit)
+		}
+	}
+	if dataOutputs > p.MaxDataOutputs {
+		return fmt.Errorf("transaction has %d data outputs, limit is %d", dataOutputs, p.MaxDataOutputs)
+	}
+
+	return nil
+}
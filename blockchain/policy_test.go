@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func standardTx() *Transaction {
+	return &Transaction{
+		Inputs:  []TxInput{{ID: []byte{1}, Out: 0}},
+		Outputs: []TxOutput{{Value: 50, PubKeyHash: bytes.Repeat([]byte{1}, pubKeyHashLength), Type: wallet.KeyP256}},
+	}
+}
+
+func TestCheckStandard(t *testing.T) {
+	policy := DefaultPolicy()
+
+	cases := []struct {
+		name   string
+		mutate func(tx *Transaction)
+		want   string
+	}{
+		{"standard", func(tx *Transaction) {}, ""},
+		{"coinbase", func(tx *Transaction) { tx.Inputs = []TxInput{{Out: -1}} }, "coinbase"},
+		{"dust", func(tx *Transaction) { tx.Outputs[0].Value = DefaultDustLimit - 1 }, "dust"},
+		{"dust limit", func(tx *Transaction) { tx.Outputs[0].Value = DefaultDustLimit }, ""},
+		{"unknown type", func(tx *Transaction) { tx.Outputs[0].Type = 0x7f }, "unknown type"},
+		{"short hash", func(tx *Transaction) { tx.Outputs[0].PubKeyHash = []byte{1} }, "unknown type"},
+		{"one data output", func(tx *Transaction) { tx.Outputs = append(tx.Outputs, TxOutput{Data: []byte("a")}) }, ""},
+		{"two data outputs", func(tx *Transaction) {
+			tx.Outputs = append(tx.Outputs, TxOutput{Data: []byte("a")}, TxOutput{Data: []byte("b")})
+		}, "data outputs"},
+		{"sigops", func(tx *Transaction) {
+			tx.Inputs = make([]TxInput, DefaultMaxSigOps+1)
+			for i := range tx.Inputs {
+				tx.Inputs[i] = TxInput{ID: []byte{1}, Out: i}
+			}
+		}, "signature operations"},
+		{"size", func(tx *Transaction) {
+			tx.Inputs[0].PubKey = make([]byte, DefaultMaxTxSize)
+		}, "size"},
+	}
+
+	for _, c := range cases {
+		tx := standardTx()
+		c.mutate(tx)
+		err := policy.CheckStandard(tx)
+		switch {
+		case c.want == "" && err != nil:
+			t.Errorf("%s: %v", c.name, err)
+		case c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)):
+			t.Errorf("%s: error %v, want %q", c.name, err, c.want)
+		}
+	}
+}
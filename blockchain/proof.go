@@ -58,7 +58,7 @@ func (pow *ProofOfWork) Run() (int, []byte) {
 	return nonce, hash[:]
 }
 
-// Validate ...
+// Validate 區塊的 Hash 必須與內容一致且達到難度
 func (pow *ProofOfWork) Validate() bool {
 	var intHash big.Int
 
@@ -66,7 +66,7 @@ func (pow *ProofOfWork) Validate() bool {
 	hash := sha256.Sum256(data)
 	intHash.SetBytes(hash[:])
 
-	return intHash.Cmp(pow.Target) == -1
+	return bytes.Equal(hash[:], pow.Block.Hash) && intHash.Cmp(pow.Target) == -1
 }
 
 // NewProof 初始化
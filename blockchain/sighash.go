@@ -0,0 +1,105 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 簽章類型, 附加在每個簽章的最後一個位元組, 決定簽章涵蓋哪些輸入與輸出
+const (
+	SigHashAll          = byte(0x01) // 涵蓋所有輸入與輸出
+	SigHashNone         = byte(0x02) // 涵蓋所有輸入, 不涵蓋任何輸出
+	SigHashSingle       = byte(0x03) // 涵蓋所有輸入, 只涵蓋與本輸入同索引的輸出
+	SigHashAnyoneCanPay = byte(0x80) // 只涵蓋本輸入, 其他人可以自行加入輸入
+
+	sigHashMask = byte(0x1f)
+)
+
+// ValidSigHashType ...
+func ValidSigHashType(hashType byte) bool {
+	base := hashType & sigHashMask
+	if hashType&^(sigHashMask|SigHashAnyoneCanPay) != 0 {
+		return false
+	}
+
+	return base == SigHashAll || base == SigHashNone || base == SigHashSingle
+}
+
+var sigHashNames = map[byte]string{
+	SigHashAll:    "ALL",
+	SigHashNone:   "NONE",
+	SigHashSingle: "SINGLE",
+}
+
+// SigHashString 例如 "ALL" 或 "SINGLE|ANYONECANPAY"
+func SigHashString(hashType byte) string {
+	name, ok := sigHashNames[hashType&sigHashMask]
+	if !ok || !ValidSigHashType(hashType) {
+		return fmt.Sprintf("UNKNOWN(%#x)", hashType)
+	}
+	if hashType&SigHashAnyoneCanPay != 0 {
+		name += "|ANYONECANPAY"
+	}
+	return name
+}
+
+// ParseSigHashType 解析 SigHashString 的格式
+func ParseSigHashType(name string) (byte, error) {
+	for hashType := range sigHashNames {
+		for _, flag := range []byte{0, SigHashAnyoneCanPay} {
+			if SigHashString(hashType|flag) == strings.ToUpper(name) {
+				return hashType | flag, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown signature hash type %q", name)
+}
+
+// SignatureHash 依照簽章類型計算第 inID 個輸入要簽署的摘要
+// prevPubKeyHash 為該輸入所花費之輸出的 PubKeyHash
+func (tx *Transaction) SignatureHash(inID int, prevPubKeyHash []byte, hashType byte) ([]byte, error) {
+	if !ValidSigHashType(hashType) {
+		return nil, errors.New("unknown signature hash type")
+	}
+	if inID < 0 || inID >= len(tx.Inputs) {
+		return nil, errors.New("input index out of range")
+	}
+
+	txCopy := tx.TrimmedCopy()
+	txCopy.ID = nil
+	txCopy.Inputs[inID].PubKey = prevPubKeyHash
+
+	// 不涵蓋全部輸出時, 其他輸入的 Sequence 也不涵蓋, 讓其他人可以自行更新
+	if hashType&sigHashMask != SigHashAll {
+		for i := range txCopy.Inputs {
+			if i != inID {
+				txCopy.Inputs[i].Sequence = 0
+			}
+		}
+	}
+
+	switch hashType & sigHashMask {
+	case SigHashNone:
+		txCopy.Outputs = nil
+	case SigHashSingle:
+		if inID >= len(txCopy.Outputs) {
+			return nil, errors.New("no output matches input for SIGHASH_SINGLE")
+		}
+		outputs := make([]TxOutput, inID+1)
+		for i := 0; i < inID; i++ {
+			outputs[i] = TxOutput{Value: -1}
+		}
+		outputs[inID] = txCopy.Outputs[inID]
+		txCopy.Outputs = outputs
+	}
+
+	if hashType&SigHashAnyoneCanPay != 0 {
+		txCopy.Inputs = []TxInput{txCopy.Inputs[inID]}
+	}
+
+	hash := sha256.Sum256(append(txCopy.Serialize(), hashType))
+
+	return hash[:], nil
+}
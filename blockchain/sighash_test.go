@@ -0,0 +1,209 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"bytes"
+	"testing"
+)
+
+// sighashTx 三個輸入三個輸出的交易, 測試都簽署第 1 個輸入
+func sighashTx() *Transaction {
+	tx := &Transaction{}
+	for i := 0; i < 3; i++ {
+		tx.Inputs = append(tx.Inputs, TxInput{
+			ID:       bytes.Repeat([]byte{byte(i + 1)}, 32),
+			Out:      i,
+			Sequence: MaxSequence,
+		})
+		tx.Outputs = append(tx.Outputs, TxOutput{
+			Value:      10 * (i + 1),
+			PubKeyHash: bytes.Repeat([]byte{byte(0xa0 + i)}, 20),
+		})
+	}
+	return tx
+}
+
+// sighashMutations 各種修改, 以及修改是否影響第 1 個輸入在各簽章類型下的摘要
+var sighashMutations = []struct {
+	name    string
+	mutate  func(tx *Transaction)
+	commits map[byte]bool
+}{
+	{
+		"own sequence",
+		func(tx *Transaction) { tx.Inputs[1].Sequence = ReplaceableSequence },
+		map[byte]bool{SigHashAll: true, SigHashNone: true, SigHashSingle: true},
+	},
+	{
+		"other input prevout",
+		func(tx *Transaction) { tx.Inputs[0].Out = 7 },
+		map[byte]bool{SigHashAll: true, SigHashNone: true, SigHashSingle: true},
+	},
+	{
+		"other input sequence",
+		func(tx *Transaction) { tx.Inputs[2].Sequence = ReplaceableSequence },
+		map[byte]bool{SigHashAll: true},
+	},
+	{
+		"added input",
+		func(tx *Transaction) { tx.Inputs = append(tx.Inputs, TxInput{ID: []byte{9}, Out: 0}) },
+		map[byte]bool{SigHashAll: true, SigHashNone: true, SigHashSingle: true},
+	},
+	{
+		"earlier output",
+		func(tx *Transaction) { tx.Outputs[0].Value++ },
+		map[byte]bool{SigHashAll: true},
+	},
+	{
+		"matching output",
+		func(tx *Transaction) { tx.Outputs[1].PubKeyHash = bytes.Repeat([]byte{0xff}, 20) },
+		map[byte]bool{SigHashAll: true, SigHashSingle: true},
+	},
+	{
+		"later output",
+		func(tx *Transaction) { tx.Outputs[2].Value++ },
+		map[byte]bool{SigHashAll: true},
+	},
+	{
+		"added output",
+		func(tx *Transaction) { tx.Outputs = append(tx.Outputs, TxOutput{Value: 1}) },
+		map[byte]bool{SigHashAll: true},
+	},
+	{
+		"signatures",
+		func(tx *Transaction) {
+			for i := range tx.Inputs {
+				tx.Inputs[i].Signature = []byte{1, 2, 3}
+				tx.Inputs[i].PubKey = []byte{4, 5, 6}
+			}
+		},
+		map[byte]bool{},
+	},
+}
+
+// anyoneCanPayExcluded ANYONECANPAY 只保留本輸入, 其他輸入的修改都不影響摘要
+var anyoneCanPayExcluded = map[string]bool{
+	"other input prevout":  true,
+	"other input sequence": true,
+	"added input":          true,
+}
+
+func TestSignatureHashCommitments(t *testing.T) {
+	prevPubKeyHash := bytes.Repeat([]byte{0x11}, 20)
+
+	for _, base := range []byte{SigHashAll, SigHashNone, SigHashSingle} {
+		for _, flag := range []byte{0, SigHashAnyoneCanPay} {
+			hashType := base | flag
+
+			digest, err := sighashTx().SignatureHash(1, prevPubKeyHash, hashType)
+			if err != nil {
+				t.Fatalf("%s: %v", SigHashString(hashType), err)
+			}
+
+			for _, m := range sighashMutations {
+				tx := sighashTx()
+				m.mutate(tx)
+				mutated, err := tx.SignatureHash(1, prevPubKeyHash, hashType)
+				if err != nil {
+					t.Fatalf("%s %s: %v", SigHashString(hashType), m.name, err)
+				}
+
+				want := m.commits[base] && !(flag != 0 && anyoneCanPayExcluded[m.name])
+				if got := !bytes.Equal(digest, mutated); got != want {
+					t.Errorf("%s: %s commits=%v, want %v", SigHashString(hashType), m.name, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSignatureHashTypeCommitted(t *testing.T) {
+	tx := sighashTx()
+	seen := make(map[string]string)
+
+	for _, base := range []byte{SigHashAll, SigHashNone, SigHashSingle} {
+		for _, flag := range []byte{0, SigHashAnyoneCanPay} {
+			digest, err := tx.SignatureHash(1, nil, base|flag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other, ok := seen[string(digest)]; ok {
+				t.Errorf("%s and %s share a digest", other, SigHashString(base|flag))
+			}
+			seen[string(digest)] = SigHashString(base | flag)
+		}
+	}
+}
+
+func TestSignatureHashSingleWithoutMatchingOutput(t *testing.T) {
+	tx := sighashTx()
+	tx.Outputs = tx.Outputs[:1]
+
+	for _, hashType := range []byte{SigHashSingle, SigHashSingle | SigHashAnyoneCanPay} {
+		if _, err := tx.SignatureHash(1, nil, hashType); err == nil {
+			t.Errorf("%s: signed input 1 with only one output", SigHashString(hashType))
+		}
+		if _, err := tx.SignatureHash(0, nil, hashType); err != nil {
+			t.Errorf("%s: input 0 has a matching output: %v", SigHashString(hashType), err)
+		}
+	}
+}
+
+func TestSignatureHashRejects(t *testing.T) {
+	tx := sighashTx()
+
+	for _, hashType := range []byte{0x00, 0x04, 0x41, SigHashAnyoneCanPay} {
+		if _, err := tx.SignatureHash(0, nil, hashType); err == nil {
+			t.Errorf("accepted hash type %#x", hashType)
+		}
+	}
+	for _, inID := range []int{-1, len(tx.Inputs)} {
+		if _, err := tx.SignatureHash(inID, nil, SigHashAll); err == nil {
+			t.Errorf("accepted input index %d", inID)
+		}
+	}
+}
+
+// TestSignInputAllKeyTypes 每種金鑰類型以每種簽章類型簽署後可驗證, 修改涵蓋的輸出後驗證失敗, 修改不涵蓋的輸出仍可驗證
+func TestSignInputAllKeyTypes(t *testing.T) {
+	for _, keyType := range []wallet.KeyType{wallet.KeyP256, wallet.KeySecp256k1, wallet.KeySchnorr} {
+		w := wallet.MakeWallet(keyType)
+		prevOut := TxOutput{Value: 50, PubKeyHash: wallet.PublicKeyHash(w.Publickey), Type: keyType}
+
+		for _, base := range []byte{SigHashAll, SigHashNone, SigHashSingle} {
+			for _, flag := range []byte{0, SigHashAnyoneCanPay} {
+				hashType := base | flag
+				name := keyType.String() + " " + SigHashString(hashType)
+
+				tx := &Transaction{
+					Inputs:  []TxInput{{ID: []byte{1}, Out: 0, PubKey: w.Publickey, Sequence: MaxSequence}},
+					Outputs: []TxOutput{{Value: 20, PubKeyHash: []byte{0xaa}}, {Value: 30, PubKeyHash: []byte{0xbb}}},
+				}
+				if err := tx.SignInput(0, w.PrivateKey, prevOut, hashType); err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				prevOuts := []TxOutput{prevOut}
+				if !tx.VerifyWithPrevOuts(prevOuts) {
+					t.Fatalf("%s: signature does not verify", name)
+				}
+
+				tx.Outputs[0].Value++
+				if got, want := tx.VerifyWithPrevOuts(prevOuts), base == SigHashNone; got != want {
+					t.Errorf("%s: verify after changing output 0 = %v, want %v", name, got, want)
+				}
+				tx.Outputs[0].Value--
+
+				tx.Outputs[1].Value++
+				if got, want := tx.VerifyWithPrevOuts(prevOuts), base != SigHashAll; got != want {
+					t.Errorf("%s: verify after changing output 1 = %v, want %v", name, got, want)
+				}
+				tx.Outputs[1].Value--
+
+				tx.Inputs[0].Signature[SignatureLength] ^= SigHashAnyoneCanPay
+				if tx.VerifyWithPrevOuts(prevOuts) {
+					t.Errorf("%s: verified with a different hash type byte", name)
+				}
+			}
+		}
+	}
+}
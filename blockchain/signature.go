@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+// SignatureLength 固定長度簽章, 不含結尾的簽章類型
+// ECDSA 為 32 bytes r + 32 bytes s, Schnorr 為 32 bytes R.x + 32 bytes s
+const SignatureLength = 64
+
+// encodeSignature 將 (r, s) 編碼為固定長度, 並把 s 正規化為 low-S 避免簽章被竄改
+func encodeSignature(curve elliptic.Curve, r, s *big.Int) []byte {
+	n := curve.Params().N
+	halfOrder := new(big.Int).Rsh(n, 1)
+
+	if s.Cmp(halfOrder) > 0 {
+		s = new(big.Int).Sub(n, s)
+	}
+
+	sig := make([]byte, SignatureLength)
+	r.FillBytes(sig[:SignatureLength/2])
+	s.FillBytes(sig[SignatureLength/2:])
+
+	return sig
+}
+
+// decodeSignature 解析固定長度簽章, 拒絕長度錯誤, 超出範圍或 high-S 的簽章
+func decodeSignature(curve elliptic.Curve, sig []byte) (*big.Int, *big.Int, error) {
+	if len(sig) != SignatureLength {
+		return nil, nil, errors.New("signature has wrong length")
+	}
+
+	n := curve.Params().N
+	halfOrder := new(big.Int).Rsh(n, 1)
+
+	r := new(big.Int).SetBytes(sig[:SignatureLength/2])
+	s := new(big.Int).SetBytes(sig[SignatureLength/2:])
+
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || s.Sign() == 0 {
+		return nil, nil, errors.New("signature is out of range")
+	}
+	if s.Cmp(halfOrder) > 0 {
+		return nil, nil, errors.New("signature is not low-S")
+	}
+
+	return r, s, nil
+}
+
+// signDigest 以輸出類型對應的演算法簽署摘要
+func signDigest(keyType wallet.KeyType, privKey *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	if !keyType.Valid() {
+		return nil, errors.New("unknown output type")
+	}
+	if privKey.Curve != keyType.Curve() {
+		return nil, errors.New("private key does not match output type")
+	}
+
+	if keyType == wallet.KeySchnorr {
+		return wallet.SchnorrSign(privKey, digest)
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, privKey, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeSignature(privKey.Curve, r, s), nil
+}
+
+// verifySignature 以輸出類型對應的演算法驗證摘要上的簽章
+func verifySignature(keyType wallet.KeyType, pubKey, digest, sig []byte) bool {
+	if keyType == wallet.KeySchnorr {
+		return wallet.SchnorrVerify(pubKey, digest, sig)
+	}
+
+	pub, err := wallet.DecodePublicKey(keyType, pubKey)
+	if err != nil {
+		return false
+	}
+
+	r, s, err := decodeSignature(pub.Curve, sig)
+	if err != nil {
+		return false
+	}
+
+	return ecdsa.Verify(pub, digest, r, s)
+}
@@ -2,16 +2,13 @@ package blockchain
 
 import (
 	"blockchain/wallet"
-	"bytes"
 	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
-	"encoding/gob"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
-	"math/big"
 	"strings"
 )
 
@@ -22,23 +19,27 @@ type Transaction struct {
 	Outputs []TxOutput
 }
 
+// Serialize 以固定的二進位格式編碼, 交易 ID 與簽章摘要都以此計算
 func (tx Transaction) Serialize() []byte {
-	var encoded bytes.Buffer
+	var e encoder
 
-	enc := gob.NewEncoder(&encoded)
-	err := enc.Encode(tx)
-	if err != nil {
-		log.Panic(err)
-	}
+	encodeTransaction(&e, &tx)
 
-	return encoded.Bytes()
+	return e.Bytes()
 }
 
+// Hash 交易 ID, 不包含簽章. 原本的 NewTransaction 就是在簽署前計算 ID, 鏈上的 ID 一直是未簽章交易的雜湊;
+// 部分簽署與 signRawTx 逐一簽署輸入時 ID 不會改變, 別人也無法只修改簽章的編碼就改變已廣播交易的 ID
 func (tx *Transaction) Hash() []byte {
 	var hash [32]byte
 
 	txCopy := *tx
 	txCopy.ID = []byte{}
+	txCopy.Inputs = make([]TxInput, len(tx.Inputs))
+	for i, in := range tx.Inputs {
+		in.Signature = nil
+		txCopy.Inputs[i] = in
+	}
 
 	hash = sha256.Sum256(txCopy.Serialize())
 
@@ -47,19 +48,18 @@ func (tx *Transaction) Hash() []byte {
 
 // SetID 使用整個 transaction 作為加密的 data
 func (tx *Transaction) SetID() {
-	var encoded bytes.Buffer
-	var hash [32]byte
-
-	encode := gob.NewEncoder(&encoded)
-	err := encode.Encode(tx)
-	ErrHandler(err)
-
-	hash = sha256.Sum256(encoded.Bytes())
-	tx.ID = hash[:]
+	tx.ID = tx.Hash()
 }
 
-// CoinbaseTx ...
-func CoinbaseTx(to, data string) *Transaction {
+const (
+	// Subsidy 每個區塊的 coinbase 可以新發行的金額
+	Subsidy = 100
+	// MaxMoney 單一輸出與單筆交易輸出總額的上限, 避免金額加總溢位
+	MaxMoney = 21000000 * Subsidy
+)
+
+// CoinbaseTx 付給 to 區塊獎勵 Subsidy 加上區塊中交易的手續費 fees
+func CoinbaseTx(to, data string, fees int) *Transaction {
 	if data == "" {
 		randData := make([]byte, 24)
 		_, err := rand.Read(randData)
@@ -70,8 +70,8 @@ func CoinbaseTx(to, data string) *Transaction {
 		data = fmt.Sprintf("%x", randData)
 	}
 
-	txin := TxInput{[]byte{}, -1, nil, []byte(data)}
-	txout := NewTXOutput(100, to)
+	txin := TxInput{[]byte{}, -1, nil, []byte(data), MaxSequence}
+	txout := NewTXOutput(Subsidy+fees, to)
 
 	tx := Transaction{nil, []TxInput{txin}, []TxOutput{*txout}}
 	tx.SetID()
@@ -79,48 +79,73 @@ func CoinbaseTx(to, data string) *Transaction {
 	return &tx
 }
 
+// IsReplaceable 任一輸入發出可取代訊號即可被取代
+func (tx *Transaction) IsReplaceable() bool {
+	for i := range tx.Inputs {
+		if tx.Inputs[i].SignalsReplacement() {
+			return true
+		}
+	}
+	return false
+}
+
+// Size 序列化後的位元組數
+func (tx *Transaction) Size() int {
+	return len(tx.Serialize())
+}
+
+// Fee 輸入總額減去輸出總額, prevOuts 與輸入一一對應
+func (tx *Transaction) Fee(prevOuts []TxOutput) int {
+	fee := 0
+	for _, out := range prevOuts {
+		fee += out.Value
+	}
+	for _, out := range tx.Outputs {
+		fee -= out.Value
+	}
+	return fee
+}
+
 // IsCoinbase ...
 func (tx *Transaction) IsCoinbase() bool {
 	return len(tx.Inputs) == 1 && len(tx.Inputs[0].ID) == 0 && tx.Inputs[0].Out == -1
 }
 
+// NewTransaction 單一收款對象的交易, 找零回到寄件者
 func NewTransaction(w *wallet.Wallet, to string, amount int, UTXO *UTXOSet) *Transaction {
-	var inputs []TxInput
-	var outputs []TxOutput
-
 	fmt.Println("Create transaction")
 
-	pubKeyHash := wallet.PublicKeyHash(w.Publickey)
-	acc, validOutputs := UTXO.FindSpendableOutputs(pubKeyHash, amount)
-	if acc < amount {
-		log.Panic("Error: not enough funds")
+	builder := TxBuilder{
+		Wallet:     w,
+		UTXO:       UTXO,
+		Recipients: []Recipient{{to, amount}},
 	}
-
-	for txID, outs := range validOutputs {
-		txID, err := hex.DecodeString(txID)
-		ErrHandler(err)
-
-		for _, out := range outs {
-			input := TxInput{txID, out, nil, w.Publickey}
-			inputs = append(inputs, input)
-		}
+	tx, err := builder.Build()
+	if err != nil {
+		log.Panic(err)
 	}
 
-	outputs = append(outputs, *NewTXOutput(amount, to))
+	fmt.Println("end to sign")
+	return tx
+}
 
-	if acc > amount {
-		outputs = append(outputs, *NewTXOutput(acc-amount, to))
+// NewDataTransaction 建立一筆帶有資料輸出的交易, 花費寄件者最少一個輸出並全數找零給寄件者
+func NewDataTransaction(w *wallet.Wallet, data []byte, UTXO *UTXOSet) (*Transaction, error) {
+	builder := TxBuilder{
+		Wallet: w,
+		UTXO:   UTXO,
+		Data:   data,
 	}
 
-	tx := Transaction{nil, inputs, outputs}
-	tx.ID = tx.Hash()
-	UTXO.BlockChain.SignTransaction(&tx, w.PrivateKey)
-
-	fmt.Println("end to sign")
-	return &tx
+	return builder.Build()
 }
 
 func (tx *Transaction) Sign(privKey ecdsa.PrivateKey, prevTXs map[string]Transaction) {
+	tx.SignWithType(privKey, prevTXs, SigHashAll)
+}
+
+// SignWithType 以指定的簽章類型簽署所有輸入
+func (tx *Transaction) SignWithType(privKey ecdsa.PrivateKey, prevTXs map[string]Transaction, hashType byte) {
 	if tx.IsCoinbase() {
 		return
 	}
@@ -131,22 +156,29 @@ func (tx *Transaction) Sign(privKey ecdsa.PrivateKey, prevTXs map[string]Transac
 		}
 	}
 
-	txCopy := tx.TrimmedCopy()
-
-	for inID, in := range txCopy.Inputs {
+	for inID, in := range tx.Inputs {
 		prevTx := prevTXs[hex.EncodeToString(in.ID)]
-		txCopy.Inputs[inID].Signature = nil
-		txCopy.Inputs[inID].PubKey = prevTx.Outputs[in.Out].PubKeyHash
-		txCopy.ID = txCopy.Hash()
-		txCopy.Inputs[inID].PubKey = nil
-
-		r, s, err := ecdsa.Sign(rand.Reader, &privKey, txCopy.ID)
+		err := tx.SignInput(inID, privKey, prevTx.Outputs[in.Out], hashType)
 		ErrHandler(err)
+	}
+}
 
-		signature := append(r.Bytes(), s.Bytes()...)
-		tx.Inputs[inID].Signature = signature
+// SignInput 只簽署第 inID 個輸入, prevOut 為該輸入所花費的輸出
+// 搭配 SigHashAnyoneCanPay 可讓多方各自加入並簽署自己的輸入
+func (tx *Transaction) SignInput(inID int, privKey ecdsa.PrivateKey, prevOut TxOutput, hashType byte) error {
+	digest, err := tx.SignatureHash(inID, prevOut.PubKeyHash, hashType)
+	if err != nil {
+		return err
 	}
 
+	signature, err := signDigest(prevOut.Type, &privKey, digest)
+	if err != nil {
+		return err
+	}
+
+	tx.Inputs[inID].Signature = append(signature, hashType)
+
+	return nil
 }
 
 // TrimmedCopy 複製一份
@@ -155,11 +187,11 @@ func (tx *Transaction) TrimmedCopy() Transaction {
 	var outputs []TxOutput
 
 	for _, in := range tx.Inputs {
-		inputs = append(inputs, TxInput{in.ID, in.Out, nil, nil})
+		inputs = append(inputs, TxInput{in.ID, in.Out, nil, nil, in.Sequence})
 	}
 
 	for _, out := range tx.Outputs {
-		outputs = append(outputs, TxOutput{out.Value, out.PubKeyHash})
+		outputs = append(outputs, TxOutput{out.Value, out.PubKeyHash, out.Data, out.Type})
 	}
 
 	txCopy := Transaction{tx.ID, inputs, outputs}
@@ -178,40 +210,83 @@ func (tx *Transaction) Verify(prevTXs map[string]Transaction) bool {
 		}
 	}
 
-	txCopy := tx.TrimmedCopy()
-	curve := elliptic.P256()
+	prevOuts, err := tx.PrevOutputs(prevTXs)
+	if err != nil {
+		return false
+	}
 
-	for inID, in := range tx.Inputs {
-		prevTx := prevTXs[hex.EncodeToString(in.ID)]
-		txCopy.Inputs[inID].Signature = nil
-		txCopy.Inputs[inID].PubKey = prevTx.Outputs[in.Out].PubKeyHash
-		txCopy.ID = txCopy.Hash()
-		txCopy.Inputs[inID].PubKey = nil
+	return tx.VerifyWithPrevOuts(prevOuts)
+}
 
-		var (
-			r big.Int = big.Int{}
-			s big.Int = big.Int{}
-			x big.Int = big.Int{}
-			y big.Int = big.Int{}
+// PrevOutputs 依輸入順序取出每個輸入所花費的輸出
+func (tx *Transaction) PrevOutputs(prevTXs map[string]Transaction) ([]TxOutput, error) {
+	var prevOuts []TxOutput
 
-			sigLen int = len(in.Signature)
-			keyLen int = len(in.PubKey)
-		)
+	for _, in := range tx.Inputs {
+		prevTx, ok := prevTXs[hex.EncodeToString(in.ID)]
+		if !ok || in.Out < 0 || in.Out >= len(prevTx.Outputs) {
+			return nil, errors.New("input references an unknown output")
+		}
+		prevOuts = append(prevOuts, prevTx.Outputs[in.Out])
+	}
 
-		r.SetBytes(in.Signature[:(sigLen / 2)])
-		s.SetBytes(in.Signature[(sigLen / 2):])
+	return prevOuts, nil
+}
 
-		x.SetBytes(in.PubKey[:(keyLen / 2)])
-		y.SetBytes(in.PubKey[(keyLen / 2):])
+// VerifyWithPrevOuts 以每個輸入所花費的輸出驗證簽章, 不需要完整的前一筆交易
+func (tx *Transaction) VerifyWithPrevOuts(prevOuts []TxOutput) bool {
+	if tx.IsCoinbase() {
+		return true
+	}
 
-		rawPubKey := ecdsa.PublicKey{curve, &x, &y}
-		if ecdsa.Verify(&rawPubKey, txCopy.ID, &r, &s) == false {
+	checks, err := tx.sigChecks(prevOuts)
+	if err != nil {
+		return false
+	}
+
+	for _, check := range checks {
+		if !check.verify() {
 			return false
 		}
 	}
 	return true
 }
 
+// sigChecks 檢查每個輸入的結構並收集需要驗證的簽章, prevOuts 與輸入一一對應
+func (tx *Transaction) sigChecks(prevOuts []TxOutput) ([]sigCheck, error) {
+	var checks []sigCheck
+
+	if len(tx.Inputs) == 0 {
+		return nil, errors.New("transaction has no inputs")
+	}
+	if len(prevOuts) != len(tx.Inputs) {
+		return nil, errors.New("previous outputs do not match inputs")
+	}
+
+	for inID, in := range tx.Inputs {
+		prevOut := prevOuts[inID]
+		if prevOut.IsData() {
+			return nil, errors.New("input spends a data output")
+		}
+		if len(in.Signature) != SignatureLength+1 {
+			return nil, errors.New("signature has wrong length")
+		}
+		if !in.UsesKey(prevOut.PubKeyHash) {
+			return nil, errors.New("public key does not match output")
+		}
+
+		hashType := in.Signature[SignatureLength]
+		digest, err := tx.SignatureHash(inID, prevOut.PubKeyHash, hashType)
+		if err != nil {
+			return nil, err
+		}
+
+		checks = append(checks, sigCheck{prevOut.Type, in.PubKey, digest, in.Signature[:SignatureLength]})
+	}
+
+	return checks, nil
+}
+
 func (tx Transaction) String() string {
 	var lines []string
 
@@ -222,22 +297,24 @@ func (tx Transaction) String() string {
 		lines = append(lines, fmt.Sprintf("        Out: %d", input.Out))
 		lines = append(lines, fmt.Sprintf("        SignatureL: %x", input.Signature))
 		lines = append(lines, fmt.Sprintf("        PubKey: %x", input.PubKey))
+		lines = append(lines, fmt.Sprintf("        Sequence: %d", input.Sequence))
 	}
 
 	for i, output := range tx.Outputs {
 		lines = append(lines, fmt.Sprintf("      Output: %d", i))
 		lines = append(lines, fmt.Sprintf("        Value: %d", output.Value))
+		lines = append(lines, fmt.Sprintf("        Type: %s", output.Type))
 		lines = append(lines, fmt.Sprintf("        Script: %x", output.PubKeyHash))
+		if output.IsData() {
+			lines = append(lines, fmt.Sprintf("        Data: %x", output.Data))
+		}
 	}
 
 	return strings.Join(lines, "\n")
 }
 
 func DeserializeTransaction(data []byte) Transaction {
-	var transaction Transaction
-
-	decoder := gob.NewDecoder(bytes.NewReader(data))
-	err := decoder.Decode(&transaction)
+	transaction, err := DecodeTransaction(data)
 	ErrHandler(err)
 
 	return transaction
@@ -3,30 +3,60 @@ package blockchain
 import (
 	"blockchain/wallet"
 	"bytes"
-	"encoding/gob"
+	"errors"
+	"fmt"
 )
 
+// MaxDataSize 資料輸出可攜帶的最大位元組數
+const MaxDataSize = 80
+
 // TxOutput ...
 type TxOutput struct {
 	Value      int
 	PubKeyHash []byte
+	Data       []byte         // 不為空時為不可花費的資料輸出
+	Type       wallet.KeyType // 花費此輸出所需的金鑰與簽章類型
 }
 
+// 輸入的 Sequence, 小於 MaxSequence-1 表示此交易在確認前可以被提高手續費的交易取代
+const (
+	MaxSequence         = uint32(0xffffffff)
+	ReplaceableSequence = MaxSequence - 2
+)
+
 // TxInput ...
 type TxInput struct {
 	ID        []byte
 	Out       int
 	Signature []byte
 	PubKey    []byte
+	Sequence  uint32
+}
+
+// SignalsReplacement ...
+func (in *TxInput) SignalsReplacement() bool {
+	return in.Sequence < MaxSequence-1
 }
 
 func NewTXOutput(value int, address string) *TxOutput {
-	txo := &TxOutput{value, nil}
+	txo := &TxOutput{value, nil, nil, wallet.KeyP256}
 	txo.Lock([]byte(address))
 
 	return txo
 }
 
+// NewDataOutput 建立不可花費的資料輸出, 用於在鏈上錨定資料
+func NewDataOutput(data []byte) (*TxOutput, error) {
+	if len(data) == 0 {
+		return nil, errors.New("data output is empty")
+	}
+	if len(data) > MaxDataSize {
+		return nil, fmt.Errorf("data output exceeds %d bytes", MaxDataSize)
+	}
+
+	return &TxOutput{0, nil, data, wallet.KeyP256}, nil
+}
+
 func (in *TxInput) UsesKey(pubKeyHash []byte) bool {
 	lockingHash := wallet.PublicKeyHash(in.PubKey)
 
@@ -34,33 +64,48 @@ func (in *TxInput) UsesKey(pubKeyHash []byte) bool {
 }
 
 func (out *TxOutput) Lock(address []byte) {
-	pubKeyHash := wallet.Base58Decode(address)
-	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-4]
+	keyType, pubKeyHash, err := wallet.DecodeAddress(string(address))
+	ErrHandler(err)
 	out.PubKeyHash = pubKeyHash
+	out.Type = keyType
 }
 
 func (out *TxOutput) IsLockedWithKey(pubKeyHash []byte) bool {
-	return bytes.Compare(out.PubKeyHash, pubKeyHash) == 0
+	return !out.IsData() && bytes.Compare(out.PubKeyHash, pubKeyHash) == 0
+}
+
+// IsData 資料輸出無法被任何人花費, 也不會進入 UTXO set
+func (out *TxOutput) IsData() bool {
+	return len(out.Data) > 0
 }
 
+// TXOutputs 一筆交易中尚未花費的輸出, Indexes 為各輸出在原交易中的位置
 type TXOutputs struct {
 	Outputs []TxOutput
+	Indexes []int
+}
+
+// Add ...
+func (tos *TXOutputs) Add(index int, out TxOutput) {
+	tos.Outputs = append(tos.Outputs, out)
+	tos.Indexes = append(tos.Indexes, index)
 }
 
 func (tos TXOutputs) Serialize() []byte {
-	var buffer bytes.Buffer
-	encode := gob.NewEncoder(&buffer)
-	err := encode.Encode(tos)
-	ErrHandler(err)
+	var e encoder
 
-	return buffer.Bytes()
+	e.writeByte(outputsEncodingVersion)
+	e.writeUvarint(uint64(len(tos.Outputs)))
+	for i := range tos.Outputs {
+		e.writeVarint(int64(tos.Indexes[i]))
+		encodeOutput(&e, &tos.Outputs[i])
+	}
+
+	return e.Bytes()
 }
 
 func DeserializeOutputs(data []byte) TXOutputs {
-	var outputs TXOutputs
-
-	decode := gob.NewDecoder(bytes.NewReader(data))
-	err := decode.Decode(&outputs)
+	outputs, err := DecodeOutputs(data)
 	ErrHandler(err)
 	return outputs
 }
@@ -106,9 +106,9 @@ func (u *UTXOSet) Update(block *Block) {
 					})
 
 					outs := DeserializeOutputs(v)
-					for outIDx, out := range outs.Outputs {
-						if outIDx != in.Out {
-							updateOuts.Outputs = append(updateOuts.Outputs, out)
+					for i, out := range outs.Outputs {
+						if outs.Indexes[i] != in.Out {
+							updateOuts.Add(outs.Indexes[i], out)
 						}
 					}
 					if len(updateOuts.Outputs) == 0 {
@@ -123,8 +123,13 @@ func (u *UTXOSet) Update(block *Block) {
 				}
 
 				newOutputs := TXOutputs{}
-				for _, out := range tx.Outputs {
-					newOutputs.Outputs = append(newOutputs.Outputs, out)
+				for outIDx, out := range tx.Outputs {
+					if !out.IsData() {
+						newOutputs.Add(outIDx, out)
+					}
+				}
+				if len(newOutputs.Outputs) == 0 {
+					continue
 				}
 
 				txID := append(utxoPrefix, tx.ID...)
@@ -220,10 +225,10 @@ func (u UTXOSet) FindSpendableOutputs(pubKeyHash []byte, amount int) (int, map[s
 			txID := hex.EncodeToString(k)
 			outs := DeserializeOutputs(v)
 
-			for outIDx, out := range outs.Outputs {
+			for i, out := range outs.Outputs {
 				if out.IsLockedWithKey(pubKeyHash) && accumulated < amount {
 					accumulated += out.Value
-					unspentOuts[txID] = append(unspentOuts[txID], outIDx)
+					unspentOuts[txID] = append(unspentOuts[txID], outs.Indexes[i])
 				}
 			}
 		}
@@ -234,3 +239,70 @@ func (u UTXOSet) FindSpendableOutputs(pubKeyHash []byte, amount int) (int, map[s
 
 	return accumulated, unspentOuts
 }
+
+// FindCoins 列出 pubKeyHash 可花費的所有輸出
+func (u UTXOSet) FindCoins(pubKeyHash []byte) []Coin {
+	var coins []Coin
+
+	db := u.BlockChain.Database
+
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(utxoPrefix); it.ValidForPrefix(utxoPrefix); it.Next() {
+			item := it.Item()
+			txID := item.KeyCopy(nil)[prefixLength:]
+			var v []byte
+			err := item.Value(func(val []byte) error {
+				v = val
+				return nil
+			})
+			ErrHandler(err)
+			outs := DeserializeOutputs(v)
+
+			for i, out := range outs.Outputs {
+				if out.IsLockedWithKey(pubKeyHash) {
+					coins = append(coins, Coin{Outpoint{txID, outs.Indexes[i]}, out})
+				}
+			}
+		}
+
+		return nil
+	})
+	ErrHandler(err)
+
+	return coins
+}
+
+// FindCoin 取得指定的未花費輸出
+func (u UTXOSet) FindCoin(op Outpoint) (Coin, error) {
+	var coin Coin
+
+	err := u.BlockChain.Database.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(append(utxoPrefix, op.TxID...))
+		if err != nil {
+			return fmt.Errorf("output %s is not unspent", op)
+		}
+
+		var v []byte
+		err = item.Value(func(val []byte) error {
+			v = val
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		outs := DeserializeOutputs(v)
+		for i, out := range outs.Outputs {
+			if outs.Indexes[i] == op.Index {
+				coin = Coin{op, out}
+				return nil
+			}
+		}
+		return fmt.Errorf("output %s is not unspent", op)
+	})
+
+	return coin, err
+}
@@ -0,0 +1,166 @@
+package blockchain
+
+import (
+	"blockchain/wallet"
+	"crypto/sha256"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultSigCacheSize 簽章快取最多保存的項目數
+const defaultSigCacheSize = 50000
+
+// sigCheck 一組待驗證的 (公鑰, 摘要, 簽章)
+type sigCheck struct {
+	keyType wallet.KeyType
+	pubKey  []byte
+	digest  []byte
+	sig     []byte
+}
+
+func (c *sigCheck) key() [32]byte {
+	h := sha256.New()
+	h.Write([]byte{byte(c.keyType)})
+	h.Write(c.pubKey)
+	h.Write(c.digest)
+	h.Write(c.sig)
+
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+func (c *sigCheck) verify() bool {
+	return verifySignature(c.keyType, c.pubKey, c.digest, c.sig)
+}
+
+// SigCache 記錄已經驗證成功的簽章, 交易在 mempool 驗證過後, 打包進區塊時不需再驗證一次
+type SigCache struct {
+	mu      sync.RWMutex
+	entries map[[32]byte]struct{}
+	max     int
+}
+
+// NewSigCache ...
+func NewSigCache(max int) *SigCache {
+	return &SigCache{entries: make(map[[32]byte]struct{}), max: max}
+}
+
+// Exists ...
+func (sc *SigCache) Exists(key [32]byte) bool {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	_, ok := sc.entries[key]
+	return ok
+}
+
+// Add 快取已滿時隨機淘汰一個項目
+func (sc *SigCache) Add(key [32]byte) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.max <= 0 {
+		return
+	}
+	if len(sc.entries) >= sc.max {
+		for k := range sc.entries {
+			delete(sc.entries, k)
+			break
+		}
+	}
+	sc.entries[key] = struct{}{}
+}
+
+// Verifier 以 worker pool 平行驗證簽章, 遇到第一個失敗就停止
+type Verifier struct {
+	Workers int
+	Cache   *SigCache
+}
+
+// NewVerifier workers 小於 1 時使用 CPU 數量
+func NewVerifier(workers int, cache *SigCache) *Verifier {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	return &Verifier{workers, cache}
+}
+
+// DefaultVerifier 區塊與 mempool 共用的驗證器與簽章快取
+var DefaultVerifier = NewVerifier(0, NewSigCache(defaultSigCacheSize))
+
+// verifyChecks 驗證所有簽章, 全部成功才回傳 true
+func (v *Verifier) verifyChecks(checks []sigCheck) bool {
+	var (
+		failed int32
+		wg     sync.WaitGroup
+		jobs   = make(chan int)
+	)
+
+	workers := v.Workers
+	if workers > len(checks) {
+		workers = len(checks)
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				if atomic.LoadInt32(&failed) != 0 {
+					continue
+				}
+
+				check := &checks[idx]
+				key := check.key()
+				if v.Cache != nil && v.Cache.Exists(key) {
+					continue
+				}
+
+				if !check.verify() {
+					atomic.StoreInt32(&failed, 1)
+					continue
+				}
+				if v.Cache != nil {
+					v.Cache.Add(key)
+				}
+			}
+		}()
+	}
+
+	for i := range checks {
+		if atomic.LoadInt32(&failed) != 0 {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return failed == 0
+}
+
+// VerifyTransactions 收集所有交易的簽章後一次平行驗證
+// prevTXs 需包含所有交易輸入所引用的前一筆交易
+func (v *Verifier) VerifyTransactions(txs []*Transaction, prevTXs map[string]Transaction) bool {
+	var checks []sigCheck
+
+	for _, tx := range txs {
+		if tx.IsCoinbase() {
+			continue
+		}
+
+		prevOuts, err := tx.PrevOutputs(prevTXs)
+		if err != nil {
+			return false
+		}
+		txChecks, err := tx.sigChecks(prevOuts)
+		if err != nil {
+			return false
+		}
+		checks = append(checks, txChecks...)
+	}
+
+	return v.verifyChecks(checks)
+}
@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"encoding/hex"
+)
+
+// TxView 交易的結構化檢視, 欄位與 Transaction.String 相同, 供 decodeRawTx 輸出 JSON
+type TxView struct {
+	ID      string         `json:"id"`
+	Inputs  []TxInputView  `json:"inputs"`
+	Outputs []TxOutputView `json:"outputs"`
+}
+
+// TxInputView ...
+type TxInputView struct {
+	TxID      string `json:"txid"`
+	Out       int    `json:"out"`
+	Signature string `json:"signature"`
+	SigHash   string `json:"sighash,omitempty"`
+	PubKey    string `json:"pubkey"`
+	Sequence  uint32 `json:"sequence"`
+}
+
+// TxOutputView ...
+type TxOutputView struct {
+	Value  int    `json:"value"`
+	Type   string `json:"type"`
+	Script string `json:"script"`
+	Data   string `json:"data,omitempty"`
+}
+
+// View ...
+func (tx Transaction) View() TxView {
+	view := TxView{
+		ID:      hex.EncodeToString(tx.ID),
+		Inputs:  []TxInputView{},
+		Outputs: []TxOutputView{},
+	}
+
+	for _, in := range tx.Inputs {
+		iv := TxInputView{
+			TxID:      hex.EncodeToString(in.ID),
+			Out:       in.Out,
+			Signature: hex.EncodeToString(in.Signature),
+			PubKey:    hex.EncodeToString(in.PubKey),
+			Sequence:  in.Sequence,
+		}
+		if !tx.IsCoinbase() && len(in.Signature) > 0 {
+			iv.SigHash = SigHashString(in.Signature[len(in.Signature)-1])
+		}
+		view.Inputs = append(view.Inputs, iv)
+	}
+
+	for _, out := range tx.Outputs {
+		ov := TxOutputView{
+			Value:  out.Value,
+			Type:   out.Type.String(),
+			Script: hex.EncodeToString(out.PubKeyHash),
+		}
+		if out.IsData() {
+			ov.Type = "data"
+			ov.Data = hex.EncodeToString(out.Data)
+		}
+		view.Outputs = append(view.Outputs, ov)
+	}
+
+	return view
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"blockchain/wallet"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// resolveContacts 把收款對象中的 @name 換成地址簿中的地址
+func resolveContacts(recipients []blockchain.Recipient, nodeID string) []blockchain.Recipient {
+	var wallets *wallet.Wallets
+
+	for i, r := range recipients {
+		if !strings.HasPrefix(r.Address, wallet.ContactPrefix) {
+			continue
+		}
+		if wallets == nil {
+			var err error
+			if wallets, err = wallet.CreateWallets(nodeID); err != nil {
+				log.Panic(err)
+			}
+		}
+
+		address, err := wallets.ResolveAddress(r.Address)
+		if err != nil {
+			log.Panic(err)
+		}
+		recipients[i].Address = address
+	}
+	return recipients
+}
+
+func (cli *CommandLine) setLabel(address, label, nodeID string) {
+	wallets := hdWallets(nodeID)
+
+	if err := wallets.SetLabel(address, label); err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("%s labeled %q\n", address, label)
+}
+
+func (cli *CommandLine) addContact(name, address, nodeID string) {
+	wallets := hdWallets(nodeID)
+
+	if err := wallets.AddContact(name, address); err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("%s%s is %s\n", wallet.ContactPrefix, name, address)
+}
+
+func (cli *CommandLine) removeContact(name, nodeID string) {
+	wallets := hdWallets(nodeID)
+
+	if err := wallets.RemoveContact(name); err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("%s%s removed\n", wallet.ContactPrefix, name)
+}
+
+func (cli *CommandLine) listContacts(nodeID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	for _, name := range wallets.ContactNames() {
+		fmt.Printf("%s%s %s\n", wallet.ContactPrefix, name, wallets.Contacts[name])
+	}
+}
+
+// exportAddressBook 匯出標籤與聯絡人, 不包含任何金鑰
+func (cli *CommandLine) exportAddressBook(out, nodeID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer file.Close()
+
+	if err := wallets.WriteAddressBook(file); err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("Address book written to %s\n", out)
+}
+
+func (cli *CommandLine) importAddressBook(in, nodeID string) {
+	file, err := os.Open(in)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer file.Close()
+
+	wallets := hdWallets(nodeID)
+	n, err := wallets.ReadAddressBook(file)
+	if err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("Imported %d address book entries\n", n)
+}
@@ -3,13 +3,19 @@ package cli
 import (
 	"blockchain/blockchain"
 	"blockchain/network"
+	"blockchain/params"
 	"blockchain/wallet"
+	"crypto/sha256"
+	"encoding/csv"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type CommandLine struct {
@@ -18,13 +24,63 @@ type CommandLine struct {
 func (cli *CommandLine) printUsage() {
 	fmt.Println("Usage: ")
 	fmt.Println(" getBalance -add ress ADDRESS - get the balance for ")
+	fmt.Println(" getBalance - get the balance of every address in the wallet, including watch-only ones")
 	fmt.Println(" createBlockchain -address ADDRESS creates a blockchain")
 	fmt.Println(" printchian - Prints the blocks in the chain")
 	fmt.Println(" send -from FROM -to TO -amount AMOUNT -mine - Send amount")
-	fmt.Println(" createWallet  - Creates a new Wallet")
+	fmt.Println(" send -from FROM -to @CONTACT -amount AMOUNT -mine - Send to an address book contact")
+	fmt.Println(" send -from FROM -to TO:AMOUNT [-to TO:AMOUNT ...] [-csv FILE] [-change ADDR | -newChange] -mine - Send to several recipients")
+	fmt.Println("      [-coinSelect largest|smallest|bnb|random] [-inputs TXID:INDEX,...] - Choose which outputs to spend")
+	fmt.Println("      [-fee FEE] [-replaceable] - Pay a fee and allow bumping it later")
+	fmt.Println("      [-unconfirmed] - Also spend outputs of pending transactions (child pays for parent)")
+	fmt.Println(" createUnsigned -from FROM -to TO:AMOUNT ... -out FILE - Build an unsigned transaction for offline signing")
+	fmt.Println(" signOffline -in FILE -out FILE - Sign a partial transaction with the keys in the wallet")
+	fmt.Println(" combine -in FILE,FILE,... -out FILE - Merge signatures from several partial transactions")
+	fmt.Println(" finalizeAndBroadcast -in FILE [-mine -miner ADDRESS] - Finalize and send a partial transaction")
+	fmt.Println(" createRawTx -inputs TXID:INDEX,... -to TO:AMOUNT ... [-data HEX] - Build an unsigned hex transaction")
+	fmt.Println(" decodeRawTx -hex HEX - Print a hex transaction as JSON")
+	fmt.Println(" signRawTx -hex HEX [-sighash ALL|NONE|SINGLE[|ANYONECANPAY]] - Sign the inputs owned by the wallet")
+	fmt.Println(" sendRawTx -hex HEX [-mine -miner ADDRESS] - Verify and broadcast a hex transaction")
+	fmt.Println(" bumpFee -txid TXID [-fee FEE] - Replace a pending replaceable transaction with a higher fee")
+	fmt.Println(" importTx -hex HEX - Track an incoming unconfirmed transaction so its outputs can be spent")
+	fmt.Println(" listTransactions [-address ADDRESS] [-category receive|send|self|generate] [-minConf N] [-count N] - List wallet transactions")
+	fmt.Println(" rescan [-from-height H] - Rebuild the wallet transaction history from a block height")
+	fmt.Println(" listUnspent -address ADDRESS - List spendable outputs as TXID:INDEX VALUE")
+	fmt.Println(" createWallet -type TYPE [-format base58|bech32] - Creates a new Wallet (p256, secp256k1, schnorr)")
 	fmt.Println(" listAddresses - List the address in our wallet file")
+	fmt.Println(" dumpPrivKey -address ADDRESS - Export the private key of an address")
+	fmt.Println(" importPrivKey -key KEY [-rescan] - Import a private key exported by dumpPrivKey")
+	fmt.Println(" signMessage -address ADDRESS -message MESSAGE - Prove ownership of an address")
+	fmt.Println(" verifyMessage -address ADDRESS -signature SIGNATURE -message MESSAGE - Check a signed message")
+	fmt.Println(" setLabel -address ADDRESS -label LABEL - Label a wallet address, an empty label removes it")
+	fmt.Println(" addContact -name NAME -address ADDRESS - Save an external address, usable as -to @NAME")
+	fmt.Println(" removeContact -name NAME - Remove a contact")
+	fmt.Println(" listContacts - List the address book")
+	fmt.Println(" exportAddressBook -out FILE - Write labels and contacts to a CSV file")
+	fmt.Println(" importAddressBook -in FILE - Read labels and contacts from a CSV file")
+	fmt.Println(" importWatch -address ADDRESS | -pubkey HEX [-type TYPE] - Track an address without its private key")
+	fmt.Println(" createNamedWallet -name NAME - Create and load a separate wallet on this node")
+	fmt.Println(" loadWallet -name NAME - Load a named wallet so -wallet NAME can use it")
+	fmt.Println(" unloadWallet -name NAME - Unload a named wallet and end its unlock period")
+	fmt.Println(" listWallets - List the named wallets of this node")
+	fmt.Println(" createHDWallet -type TYPE [-words 12|15|18|21|24] [-seedPassphrase PASS] - Create an HD wallet with a mnemonic backup")
+	fmt.Println(" restoreHDWallet -mnemonic \"WORDS\" -type TYPE [-seedPassphrase PASS] [-gap N] - Restore an HD wallet and find its used addresses")
+	fmt.Println(" rescanWallet [-gap N] - Find used HD addresses on chain")
+	fmt.Println(" encryptWallet [-passphrase PASS] - Encrypt the private keys in the wallet file")
+	fmt.Println(" changePassphrase [-old PASS] [-new PASS] - Re-encrypt the wallet with a new passphrase")
+	fmt.Println(" unlockWallet [-passphrase PASS] [-timeout SECONDS] - Allow signing for a limited time")
+	fmt.Println("   passphrases that are not given as flags are read from standard input")
+	fmt.Println(" lockWallet - Lock the wallet before the timeout")
 	fmt.Println(" ReIndexUTXO - Rebuild the UTXO set")
 	fmt.Println(" startNode - miner ADDRESS - Start a node with ID specified in NODE_ID env.")
+	fmt.Println("      [-dustLimit N] [-maxTxSize BYTES] [-maxSigOps N] [-maxDataOutputs N] [-incrementalRelayFee FEE] - Mempool standardness policy")
+	fmt.Println(" consolidate -address ADDRESS [-max-inputs N] [-min-value V] [-fee FEE] -mine - Merge small outputs into one")
+	fmt.Println(" sweep -from FROM -to TO [-fee FEE] -mine - Move every output of an address to another address")
+	fmt.Println(" anchor -file PATH -from FROM -mine - Anchor the SHA-256 of a file on chain")
+	fmt.Println(" verifyAnchor -file PATH - Find the block that anchored a file")
+	fmt.Println("Wallet commands accept -wallet NAME to use a loaded named wallet instead of the default one")
+	fmt.Println("Set NETWORK to mainnet (default), testnet or regtest; each network has its own addresses, keys and data files")
+	fmt.Println("The seed node listens on port 3000 on mainnet, 13000 on testnet and 23000 on regtest; start it with that NODE_ID")
 }
 
 func (cli *CommandLine) validateArgs() {
@@ -69,8 +125,8 @@ func (cli *CommandLine) printChain(nodeID string) {
 	}
 }
 
-func (cli CommandLine) StartNode(nodeID, minerAddress string) {
-	fmt.Println("Start Node %s\n", nodeID)
+func (cli CommandLine) StartNode(nodeID, minerAddress string, policy blockchain.Policy) {
+	fmt.Printf("Start Node %s\n", nodeID)
 
 	if len(minerAddress) > 0 {
 		if wallet.ValidateAddress(minerAddress) {
@@ -79,10 +135,15 @@ func (cli CommandLine) StartNode(nodeID, minerAddress string) {
 			log.Panic("Wrong miner address !")
 		}
 	}
-	network.StartServer(nodeID, minerAddress)
+	network.StartServer(nodeID, minerAddress, policy)
 }
 
 func (cli *CommandLine) createBlockChain(nodeID string, address string) {
+	// 先檢查地址, 避免其他網路的地址留下沒有創世區塊的資料庫
+	if _, _, err := wallet.DecodeAddress(address); err != nil {
+		log.Panic(err)
+	}
+
 	chain := blockchain.InitBlockChain(address, nodeID)
 	defer chain.Database.Close()
 
@@ -92,15 +153,28 @@ func (cli *CommandLine) createBlockChain(nodeID string, address string) {
 	fmt.Println("Finished!")
 }
 
-func (cli *CommandLine) createWallet(nodeID string) {
+func (cli *CommandLine) createWallet(nodeID, keyType, format string) {
+	t, err := wallet.ParseKeyType(keyType)
+	if err != nil {
+		log.Panic(err)
+	}
+	f, err := wallet.ParseAddressFormat(format)
+	if err != nil {
+		log.Panic(err)
+	}
+
 	ws, err := wallet.CreateWallets(nodeID)
 	if err != nil {
 		log.Print(err)
 	}
 
-	address := ws.AddWallet()
+	address := ws.AddWallet(t)
 	ws.SaveFile(nodeID)
 
+	address, err = wallet.FormatAddress(address, f)
+	if err != nil {
+		log.Panic(err)
+	}
 	fmt.Println("address is " + address)
 
 	fmt.Println("Finished!")
@@ -112,27 +186,35 @@ func (cli *CommandLine) listAddresses(nodeID string) {
 		log.Panic(err)
 	}
 
-	fmt.Println(wallets.Wallets)
-	addresses := wallets.GetAllAddresses()
+	addresses := wallets.WatchedAddresses()
 
 	for _, address := range addresses {
-		fmt.Println(address)
+		line := address
+		if label := wallets.Label(address); label != "" {
+			line += " " + label
+		}
+		if wallets.IsWatched(address) {
+			line += " (watch-only)"
+		}
+		fmt.Println(line)
 	}
 	fmt.Println("Finished!")
 }
 func (cli *CommandLine) getBalance(address string, nodeID string) {
-	if !wallet.ValidateAddress(address) {
-		log.Panic("Address is not Valid")
+	if _, _, err := wallet.DecodeAddress(address); err != nil {
+		log.Panic("Address is not Valid: ", err)
 	}
 
 	chain := blockchain.ContinueBlockChain(nodeID)
 	defer chain.Database.Close()
 
-	UTXOSet := blockchain.UTXOSet{chain}
+	UTXOSet := blockchain.UTXOSet{BlockChain: chain}
 
 	balance := 0
-	pubKeyHash := wallet.Base58Decode([]byte(address))
-	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-4]
+	_, pubKeyHash, err := wallet.DecodeAddress(address)
+	if err != nil {
+		log.Panic(err)
+	}
 	utxos := UTXOSet.FindUnspentTransactions(pubKeyHash)
 
 	for _, out := range utxos {
@@ -142,13 +224,109 @@ func (cli *CommandLine) getBalance(address string, nodeID string) {
 	fmt.Printf("Balance of %s: %d\n", address, balance)
 }
 
-func (cli *CommandLine) send(from, to string, amount int, nodeID string, mineNow bool) {
+// recipientFlags 可重複指定的 -to 參數
+type recipientFlags []string
+
+func (r *recipientFlags) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *recipientFlags) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+// parseRecipients 合併 -to 與 -csv 的收款對象; 只有一個不含金額的 -to 時使用 -amount
+func parseRecipients(to []string, amount int, csvPath string) ([]blockchain.Recipient, error) {
+	var recipients []blockchain.Recipient
+
+	if len(to) == 1 && !strings.Contains(to[0], ":") {
+		recipients = append(recipients, blockchain.Recipient{Address: to[0], Amount: amount})
+		to = nil
+	}
+
+	for _, entry := range to {
+		r, err := blockchain.ParseRecipient(entry)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+
+	if csvPath != "" {
+		file, err := os.Open(csvPath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		records, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if len(record) != 2 {
+				return nil, fmt.Errorf("csv line %v is not address,amount", record)
+			}
+			r, err := blockchain.ParseRecipient(record[0] + ":" + record[1])
+			if err != nil {
+				return nil, err
+			}
+			recipients = append(recipients, r)
+		}
+	}
+
+	return recipients, nil
+}
+
+func (cli *CommandLine) listUnspent(address, nodeID string) {
+	_, pubKeyHash, err := wallet.DecodeAddress(address)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+	UTXOSet := blockchain.UTXOSet{BlockChain: chain}
+
+	for _, coin := range UTXOSet.FindCoins(pubKeyHash) {
+		fmt.Printf("%s %d\n", coin.Outpoint, coin.Output.Value)
+	}
+}
+
+// parseCoinOptions 解析 -coinSelect 與 -inputs 參數
+func parseCoinOptions(coinSelect, inputs string) (blockchain.CoinSelector, []blockchain.Outpoint) {
+	selector, err := blockchain.CoinSelectorByName(coinSelect)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	var outpoints []blockchain.Outpoint
+	if inputs != "" {
+		for _, entry := range strings.Split(inputs, ",") {
+			op, err := blockchain.ParseOutpoint(entry)
+			if err != nil {
+				log.Panic(err)
+			}
+			outpoints = append(outpoints, op)
+		}
+	}
+
+	return selector, outpoints
+}
+
+func (cli *CommandLine) send(from string, recipients []blockchain.Recipient, change string, newChange bool, coinSelect, inputs string, fee int, replaceable, spendUnconfirmed bool, nodeID string, mineNow bool) {
 	if !wallet.ValidateAddress(from) {
 		log.Panic("from addres is not valid ")
 	}
+	if spendUnconfirmed && mineNow {
+		log.Panic("-unconfirmed cannot be combined with -mine, the parent transactions are not on chain")
+	}
 
-	if !wallet.ValidateAddress(to) {
-		log.Panic("to addres is not valid ")
+	for _, r := range recipients {
+		if !wallet.ValidateAddress(r.Address) {
+			log.Panic("to addres is not valid ")
+		}
 	}
 
 	chain := blockchain.ContinueBlockChain(nodeID)
@@ -157,33 +335,128 @@ func (cli *CommandLine) send(from, to string, amount int, nodeID string, mineNow
 
 	fmt.Println("Continue block chain")
 
-	wallets, err := wallet.CreateWallets(nodeID)
+	wallets := signingWallets(nodeID)
+	fmt.Println("get wallets")
+
+	fmt.Printf("from wallet is %s\n", from)
+
+	w, err := wallets.GetSigningWallet(from)
 	if err != nil {
 		log.Panic(err)
 	}
-	fmt.Println("get wallets")
 
-	fmt.Println(wallets.Wallets)
-	fmt.Printf("from wallet is %s\n", from)
+	// 新的找零地址在交易建立成功後才寫入錢包檔案, 失敗的 send 不會推進 HD 找零鏈
+	if newChange {
+		change = wallets.AddChangeWallet(w.Type)
+	}
 
-	wallet := wallets.GetWallet(from)
+	selector, outpoints := parseCoinOptions(coinSelect, inputs)
 
-	fmt.Println("get wallet")
+	builder := blockchain.TxBuilder{
+		Wallet:        &w,
+		UTXO:          &UTXOSet,
+		Recipients:    recipients,
+		ChangeAddress: change,
+		Selector:      selector,
+		Inputs:        outpoints,
+		Fee:           fee,
+		Replaceable:   replaceable,
+	}
+	if spendUnconfirmed {
+		builder.Unconfirmed = pendingTransactions(nodeID, chain)
+	}
+	tx, err := builder.Build()
+	if err != nil {
+		log.Panic(err)
+	}
+	if newChange {
+		wallets.SaveFile(nodeID)
+		fmt.Printf("change address is %s\n", change)
+	}
 
-	tx := blockchain.NewTransaction(&wallet, to, amount, &UTXOSet)
 	if mineNow {
-		cbTx := blockchain.CoinbaseTx(from, "")
-		txs := []*blockchain.Transaction{cbTx, tx}
-		block := chain.MineBlock(txs)
+		block := mineBlock(chain, from, tx)
 		UTXOSet.Update(block)
 	} else {
 		network.SendTx(network.KnownNodes[0], tx)
-		fmt.Println("send tx")
+		addPending(nodeID, tx)
+		fmt.Printf("send tx %x\n", tx.ID)
 	}
 
 	fmt.Println("Success!")
 }
 
+// mineBlock 在本機挖出包含 txs 的區塊, coinbase 付給 miner 區塊獎勵加上 txs 的手續費
+func mineBlock(chain *blockchain.BlockChain, miner string, txs ...*blockchain.Transaction) *blockchain.Block {
+	fees, err := chain.Fees(txs)
+	if err != nil {
+		log.Panic(err)
+	}
+	cbTx := blockchain.CoinbaseTx(miner, "", fees)
+
+	return chain.MineBlock(append([]*blockchain.Transaction{cbTx}, txs...))
+}
+
+func fileHash(path string) []byte {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Panic(err)
+	}
+	hash := sha256.Sum256(content)
+
+	return hash[:]
+}
+
+func (cli *CommandLine) anchor(path, from, nodeID string, mineNow bool) {
+	if !wallet.ValidateAddress(from) {
+		log.Panic("from addres is not valid ")
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+	UTXOSet := blockchain.UTXOSet{BlockChain: chain}
+
+	wallets := signingWallets(nodeID)
+	w, err := wallets.GetSigningWallet(from)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	hash := fileHash(path)
+	tx, err := blockchain.NewDataTransaction(&w, hash, &UTXOSet)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if mineNow {
+		block := mineBlock(chain, from, tx)
+		UTXOSet.Update(block)
+	} else {
+		network.SendTx(network.KnownNodes[0], tx)
+		fmt.Println("send tx")
+	}
+
+	fmt.Printf("Anchored %x in transaction %x\n", hash, tx.ID)
+}
+
+func (cli *CommandLine) verifyAnchor(path, nodeID string) {
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	hash := fileHash(path)
+	block, tx, err := chain.FindData(hash)
+	if err != nil {
+		fmt.Printf("%x is not anchored\n", hash)
+		return
+	}
+
+	fmt.Printf("Hash: %x\n", hash)
+	fmt.Printf("Transaction: %x\n", tx.ID)
+	fmt.Printf("Block: %x\n", block.Hash)
+	fmt.Printf("Height: %d\n", block.Height)
+	fmt.Printf("Timestamp: %s\n", time.Unix(int64(block.Timestamp), 0).UTC().Format(time.RFC3339))
+}
+
 // Run ...
 func (cli *CommandLine) Run() {
 	cli.validateArgs()
@@ -193,6 +466,13 @@ func (cli *CommandLine) Run() {
 		fmt.Printf("NODE_ID env is not set!")
 		runtime.Goexit()
 	}
+	if err := wallet.ValidateNodeID(nodeID); err != nil {
+		log.Panic(err)
+	}
+	if err := params.SelectFromEnv(); err != nil {
+		log.Panic(err)
+	}
+	network.UseSeedNode()
 
 	gbCmd := flag.NewFlagSet("getBalance", flag.ExitOnError)
 	createBlockCmd := flag.NewFlagSet("createBlockchain", flag.ExitOnError)
@@ -200,16 +480,169 @@ func (cli *CommandLine) Run() {
 	printChainCmd := flag.NewFlagSet("printChain", flag.ExitOnError)
 	createWalletCmd := flag.NewFlagSet("createWallet", flag.ExitOnError)
 	listAddressesCmd := flag.NewFlagSet("listAddresses", flag.ExitOnError)
+	importWatchCmd := flag.NewFlagSet("importWatch", flag.ExitOnError)
+	signMessageCmd := flag.NewFlagSet("signMessage", flag.ExitOnError)
+	verifyMessageCmd := flag.NewFlagSet("verifyMessage", flag.ExitOnError)
+	setLabelCmd := flag.NewFlagSet("setLabel", flag.ExitOnError)
+	addContactCmd := flag.NewFlagSet("addContact", flag.ExitOnError)
+	removeContactCmd := flag.NewFlagSet("removeContact", flag.ExitOnError)
+	listContactsCmd := flag.NewFlagSet("listContacts", flag.ExitOnError)
+	exportAddressBookCmd := flag.NewFlagSet("exportAddressBook", flag.ExitOnError)
+	importAddressBookCmd := flag.NewFlagSet("importAddressBook", flag.ExitOnError)
+	listTransactionsCmd := flag.NewFlagSet("listTransactions", flag.ExitOnError)
+	rescanCmd := flag.NewFlagSet("rescan", flag.ExitOnError)
+	dumpPrivKeyCmd := flag.NewFlagSet("dumpPrivKey", flag.ExitOnError)
+	importPrivKeyCmd := flag.NewFlagSet("importPrivKey", flag.ExitOnError)
 	ReIndexUTXOCmd := flag.NewFlagSet("ReIndexUTXO", flag.ExitOnError)
 	startNodeCmd := flag.NewFlagSet("startNode", flag.ExitOnError)
+	listUnspentCmd := flag.NewFlagSet("listUnspent", flag.ExitOnError)
+	createHDWalletCmd := flag.NewFlagSet("createHDWallet", flag.ExitOnError)
+	restoreHDWalletCmd := flag.NewFlagSet("restoreHDWallet", flag.ExitOnError)
+	rescanWalletCmd := flag.NewFlagSet("rescanWallet", flag.ExitOnError)
+	encryptWalletCmd := flag.NewFlagSet("encryptWallet", flag.ExitOnError)
+	changePassphraseCmd := flag.NewFlagSet("changePassphrase", flag.ExitOnError)
+	unlockWalletCmd := flag.NewFlagSet("unlockWallet", flag.ExitOnError)
+	lockWalletCmd := flag.NewFlagSet("lockWallet", flag.ExitOnError)
+	unlockAgentCmd := flag.NewFlagSet(unlockAgentCommand, flag.ExitOnError)
+	bumpFeeCmd := flag.NewFlagSet("bumpFee", flag.ExitOnError)
+	importTxCmd := flag.NewFlagSet("importTx", flag.ExitOnError)
+	createRawTxCmd := flag.NewFlagSet("createRawTx", flag.ExitOnError)
+	decodeRawTxCmd := flag.NewFlagSet("decodeRawTx", flag.ExitOnError)
+	signRawTxCmd := flag.NewFlagSet("signRawTx", flag.ExitOnError)
+	sendRawTxCmd := flag.NewFlagSet("sendRawTx", flag.ExitOnError)
+	createUnsignedCmd := flag.NewFlagSet("createUnsigned", flag.ExitOnError)
+	signOfflineCmd := flag.NewFlagSet("signOffline", flag.ExitOnError)
+	combineCmd := flag.NewFlagSet("combine", flag.ExitOnError)
+	finalizeCmd := flag.NewFlagSet("finalizeAndBroadcast", flag.ExitOnError)
+	consolidateCmd := flag.NewFlagSet("consolidate", flag.ExitOnError)
+	sweepCmd := flag.NewFlagSet("sweep", flag.ExitOnError)
+	anchorCmd := flag.NewFlagSet("anchor", flag.ExitOnError)
+	verifyAnchorCmd := flag.NewFlagSet("verifyAnchor", flag.ExitOnError)
+	createNamedWalletCmd := flag.NewFlagSet("createNamedWallet", flag.ExitOnError)
+	loadWalletCmd := flag.NewFlagSet("loadWallet", flag.ExitOnError)
+	unloadWalletCmd := flag.NewFlagSet("unloadWallet", flag.ExitOnError)
+	listWalletsCmd := flag.NewFlagSet("listWallets", flag.ExitOnError)
+
+	// 使用錢包的命令都可以用 -wallet 選擇已載入的具名錢包
+	walletNames := make(map[*flag.FlagSet]*string)
+	for _, cmd := range []*flag.FlagSet{
+		gbCmd, sendCmd, createWalletCmd, listAddressesCmd, importWatchCmd, signMessageCmd,
+		setLabelCmd, addContactCmd, removeContactCmd, listContactsCmd, exportAddressBookCmd, importAddressBookCmd,
+		listTransactionsCmd, rescanCmd, dumpPrivKeyCmd, importPrivKeyCmd,
+		createHDWalletCmd, restoreHDWalletCmd, rescanWalletCmd,
+		encryptWalletCmd, changePassphraseCmd, unlockWalletCmd, lockWalletCmd, unlockAgentCmd,
+		bumpFeeCmd, importTxCmd, createRawTxCmd, signRawTxCmd, createUnsignedCmd, signOfflineCmd,
+		consolidateCmd, sweepCmd, anchorCmd,
+	} {
+		walletNames[cmd] = cmd.String("wallet", "", "named wallet to use, empty for the default wallet")
+	}
+	createNamedWalletName := createNamedWalletCmd.String("name", "", "wallet name")
+	loadWalletName := loadWalletCmd.String("name", "", "wallet name")
+	unloadWalletName := unloadWalletCmd.String("name", "", "wallet name")
 
 	getBalanceAddress := gbCmd.String("address", "", "get address balance")
 	createBlockchainAddress := createBlockCmd.String("address", "", "create block with address")
 	sendFrom := sendCmd.String("from", "", "source wallet address")
-	sendTo := sendCmd.String("to", "", "destination wallet address")
+	var sendTo recipientFlags
+	sendCmd.Var(&sendTo, "to", "destination address, or address:amount (repeatable)")
 	sendAmount := sendCmd.Int("amount", 0, "amount to send")
+	sendCSV := sendCmd.String("csv", "", "CSV file of address,amount recipients")
+	sendChange := sendCmd.String("change", "", "change address (default: the from address)")
+	sendNewChange := sendCmd.Bool("newChange", false, "send change to a fresh address in the wallet")
+	sendCoinSelect := sendCmd.String("coinSelect", "largest", "coin selection: largest, smallest, bnb or random")
+	sendInputs := sendCmd.String("inputs", "", "comma separated txid:index outputs to spend (coin control)")
+	sendFee := sendCmd.Int("fee", 0, "fee paid to the miner")
+	sendReplaceable := sendCmd.Bool("replaceable", false, "allow replacing the transaction with a higher fee (bumpFee)")
+	sendUnconfirmed := sendCmd.Bool("unconfirmed", false, "also spend outputs of pending transactions")
 	sendMine := sendCmd.Bool("mine", false, "Mine immediately on the same node")
+	createWalletType := createWalletCmd.String("type", wallet.DefaultKeyType.String(), "key type: p256, secp256k1 or schnorr")
+	createWalletFormat := createWalletCmd.String("format", wallet.AddressBase58.String(), "address format to display: base58 or bech32")
 	startNodeMiner := startNodeCmd.String("miner", "", "Enable mining mode and send reward to ")
+	startNodeDustLimit := startNodeCmd.Int("dustLimit", blockchain.DefaultDustLimit, "reject outputs below this value from the mempool")
+	startNodeMaxTxSize := startNodeCmd.Int("maxTxSize", blockchain.DefaultMaxTxSize, "reject larger transactions (bytes) from the mempool")
+	startNodeMaxSigOps := startNodeCmd.Int("maxSigOps", blockchain.DefaultMaxSigOps, "reject transactions with more signature operations from the mempool")
+	startNodeMaxDataOutputs := startNodeCmd.Int("maxDataOutputs", blockchain.DefaultMaxDataOutputs, "reject transactions with more data outputs from the mempool")
+	startNodeIncrementalRelayFee := startNodeCmd.Int("incrementalRelayFee", blockchain.DefaultIncrementalRelayFee, "extra fee a replacement must pay over the transactions it evicts")
+	unsignedFrom := createUnsignedCmd.String("from", "", "source address")
+	var unsignedTo recipientFlags
+	createUnsignedCmd.Var(&unsignedTo, "to", "destination address, or address:amount (repeatable)")
+	unsignedAmount := createUnsignedCmd.Int("amount", 0, "amount to send")
+	unsignedCSV := createUnsignedCmd.String("csv", "", "CSV file of address,amount recipients")
+	unsignedChange := createUnsignedCmd.String("change", "", "change address (default: the from address)")
+	unsignedCoinSelect := createUnsignedCmd.String("coinSelect", "largest", "coin selection: largest, smallest, bnb or random")
+	unsignedInputs := createUnsignedCmd.String("inputs", "", "comma separated txid:index outputs to spend (coin control)")
+	unsignedOut := createUnsignedCmd.String("out", "", "file to write the partial transaction to")
+	signOfflineIn := signOfflineCmd.String("in", "", "partial transaction file")
+	signOfflineOut := signOfflineCmd.String("out", "", "file to write the signed partial transaction to")
+	combineIn := combineCmd.String("in", "", "comma separated partial transaction files")
+	combineOut := combineCmd.String("out", "", "file to write the combined partial transaction to")
+	finalizeIn := finalizeCmd.String("in", "", "partial transaction file")
+	finalizeMine := finalizeCmd.Bool("mine", false, "Mine immediately on the same node")
+	finalizeMiner := finalizeCmd.String("miner", "", "address receiving the reward when mining")
+	rawInputs := createRawTxCmd.String("inputs", "", "comma separated txid:index outputs to spend")
+	var rawTo recipientFlags
+	createRawTxCmd.Var(&rawTo, "to", "destination address:amount (repeatable)")
+	rawData := createRawTxCmd.String("data", "", "hex data for an unspendable data output")
+	decodeRawTxHex := decodeRawTxCmd.String("hex", "", "hex encoded transaction")
+	signRawTxHex := signRawTxCmd.String("hex", "", "hex encoded transaction")
+	signRawTxSigHash := signRawTxCmd.String("sighash", "ALL", "signature hash type")
+	sendRawTxHex := sendRawTxCmd.String("hex", "", "hex encoded transaction")
+	sendRawTxMine := sendRawTxCmd.Bool("mine", false, "Mine immediately on the same node")
+	sendRawTxMiner := sendRawTxCmd.String("miner", "", "address receiving the reward when mining")
+	bumpFeeTxID := bumpFeeCmd.String("txid", "", "pending transaction to replace")
+	bumpFeeFee := bumpFeeCmd.Int("fee", 0, "new absolute fee (default: replaced fees plus the incremental relay fee)")
+	importTxHex := importTxCmd.String("hex", "", "hex encoded transaction")
+	listUnspentAddress := listUnspentCmd.String("address", "", "address to list")
+	dumpPrivKeyAddress := dumpPrivKeyCmd.String("address", "", "address whose private key is exported")
+	importPrivKeyKey := importPrivKeyCmd.String("key", "", "private key exported by dumpPrivKey")
+	importPrivKeyRescan := importPrivKeyCmd.Bool("rescan", false, "list the unspent outputs of the imported key")
+	listTransactionsAddress := listTransactionsCmd.String("address", "", "only transactions paying this address")
+	listTransactionsCategory := listTransactionsCmd.String("category", "", "receive, send, self or generate")
+	listTransactionsMinConf := listTransactionsCmd.Int("minConf", 0, "minimum confirmations")
+	listTransactionsCount := listTransactionsCmd.Int("count", 0, "only the latest N transactions, 0 for all")
+	rescanFromHeight := rescanCmd.Int("from-height", 0, "block height to rescan from")
+	signMessageAddress := signMessageCmd.String("address", "", "address whose key signs the message")
+	signMessageMessage := signMessageCmd.String("message", "", "message to sign")
+	verifyMessageAddress := verifyMessageCmd.String("address", "", "address that signed the message")
+	verifyMessageSignature := verifyMessageCmd.String("signature", "", "signature printed by signMessage")
+	verifyMessageMessage := verifyMessageCmd.String("message", "", "signed message")
+	setLabelAddress := setLabelCmd.String("address", "", "wallet address to label")
+	setLabelLabel := setLabelCmd.String("label", "", "label, empty to remove")
+	addContactName := addContactCmd.String("name", "", "contact name")
+	addContactAddress := addContactCmd.String("address", "", "contact address")
+	removeContactName := removeContactCmd.String("name", "", "contact name")
+	exportAddressBookOut := exportAddressBookCmd.String("out", "", "CSV file to write")
+	importAddressBookIn := importAddressBookCmd.String("in", "", "CSV file to read")
+	importWatchAddress := importWatchCmd.String("address", "", "address to watch")
+	importWatchPubKey := importWatchCmd.String("pubkey", "", "hex public key to watch")
+	importWatchType := importWatchCmd.String("type", wallet.DefaultKeyType.String(), "key type of -pubkey")
+	createHDWalletType := createHDWalletCmd.String("type", wallet.DefaultKeyType.String(), "key type: p256, secp256k1 or schnorr")
+	createHDWalletWords := createHDWalletCmd.Int("words", 12, "number of mnemonic words")
+	createHDWalletSeedPassphrase := createHDWalletCmd.String("seedPassphrase", "", "optional BIP39 passphrase, required again when restoring")
+	restoreHDWalletMnemonic := restoreHDWalletCmd.String("mnemonic", "", "space separated mnemonic words")
+	restoreHDWalletType := restoreHDWalletCmd.String("type", wallet.DefaultKeyType.String(), "key type the wallet was created with")
+	restoreHDWalletSeedPassphrase := restoreHDWalletCmd.String("seedPassphrase", "", "BIP39 passphrase used when creating the wallet")
+	restoreHDWalletGap := restoreHDWalletCmd.Int("gap", wallet.DefaultGapLimit, "stop after this many consecutive unused addresses")
+	rescanWalletGap := rescanWalletCmd.Int("gap", wallet.DefaultGapLimit, "stop after this many consecutive unused addresses")
+	encryptWalletPassphrase := encryptWalletCmd.String("passphrase", "", "passphrase protecting the private keys, read from standard input when omitted")
+	changePassphraseOld := changePassphraseCmd.String("old", "", "current passphrase, read from standard input when omitted")
+	changePassphraseNew := changePassphraseCmd.String("new", "", "new passphrase, read from standard input when omitted")
+	unlockWalletPassphrase := unlockWalletCmd.String("passphrase", "", "wallet passphrase, read from standard input when omitted")
+	unlockWalletTimeout := unlockWalletCmd.Int("timeout", 60, "seconds until the wallet locks again")
+	unlockAgentTimeout := unlockAgentCmd.Int("timeout", 60, "seconds until the wallet locks again")
+	consolidateAddress := consolidateCmd.String("address", "", "wallet address to consolidate")
+	consolidateMaxInputs := consolidateCmd.Int("max-inputs", 0, "maximum outputs to merge (0: policy limit)")
+	consolidateMinValue := consolidateCmd.Int("min-value", 0, "skip outputs below this value")
+	consolidateFee := consolidateCmd.Int("fee", 0, "fee paid to the miner")
+	consolidateMine := consolidateCmd.Bool("mine", false, "Mine immediately on the same node")
+	sweepFrom := sweepCmd.String("from", "", "wallet address to empty")
+	sweepTo := sweepCmd.String("to", "", "destination address")
+	sweepFee := sweepCmd.Int("fee", 0, "fee paid to the miner for each transaction")
+	sweepMine := sweepCmd.Bool("mine", false, "Mine immediately on the same node")
+	anchorFile := anchorCmd.String("file", "", "file to anchor")
+	anchorFrom := anchorCmd.String("from", "", "wallet address paying for the anchor")
+	anchorMine := anchorCmd.Bool("mine", false, "Mine immediately on the same node")
+	verifyAnchorFile := verifyAnchorCmd.String("file", "", "file to look up")
 
 	switch os.Args[1] {
 	case "getBalance":
@@ -230,20 +663,176 @@ func (cli *CommandLine) Run() {
 	case "listAddresses":
 		err := listAddressesCmd.Parse(os.Args[2:])
 		blockchain.ErrHandler(err)
+	case "dumpPrivKey":
+		err := dumpPrivKeyCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "importPrivKey":
+		err := importPrivKeyCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "listTransactions":
+		err := listTransactionsCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "rescan":
+		err := rescanCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "signMessage":
+		err := signMessageCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "verifyMessage":
+		err := verifyMessageCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "setLabel":
+		err := setLabelCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "addContact":
+		err := addContactCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "removeContact":
+		err := removeContactCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "listContacts":
+		err := listContactsCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "exportAddressBook":
+		err := exportAddressBookCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "importAddressBook":
+		err := importAddressBookCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "importWatch":
+		err := importWatchCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
 	case "ReIndexUTXO":
 		err := ReIndexUTXOCmd.Parse(os.Args[2:])
 		blockchain.ErrHandler(err)
 	case "startNode":
 		err := startNodeCmd.Parse(os.Args[2:])
 		blockchain.ErrHandler(err)
+	case "createUnsigned":
+		err := createUnsignedCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "signOffline":
+		err := signOfflineCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "combine":
+		err := combineCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "finalizeAndBroadcast":
+		err := finalizeCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "createRawTx":
+		err := createRawTxCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "decodeRawTx":
+		err := decodeRawTxCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "signRawTx":
+		err := signRawTxCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "sendRawTx":
+		err := sendRawTxCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "bumpFee":
+		err := bumpFeeCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "importTx":
+		err := importTxCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "listUnspent":
+		err := listUnspentCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "createHDWallet":
+		err := createHDWalletCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "restoreHDWallet":
+		err := restoreHDWalletCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "rescanWallet":
+		err := rescanWalletCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "encryptWallet":
+		err := encryptWalletCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "changePassphrase":
+		err := changePassphraseCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "unlockWallet":
+		err := unlockWalletCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "lockWallet":
+		err := lockWalletCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case unlockAgentCommand:
+		err := unlockAgentCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "consolidate":
+		err := consolidateCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "sweep":
+		err := sweepCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "anchor":
+		err := anchorCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "verifyAnchor":
+		err := verifyAnchorCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "createNamedWallet":
+		err := createNamedWalletCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "loadWallet":
+		err := loadWalletCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "unloadWallet":
+		err := unloadWalletCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
+	case "listWallets":
+		err := listWalletsCmd.Parse(os.Args[2:])
+		blockchain.ErrHandler(err)
 	default:
 		cli.printUsage()
 		runtime.Goexit()
 	}
 
+	for cmd, name := range walletNames {
+		if cmd.Parsed() {
+			if err := wallet.Select(nodeID, *name); err != nil {
+				log.Panic(err)
+			}
+		}
+	}
+
+	if createNamedWalletCmd.Parsed() {
+		if *createNamedWalletName == "" {
+			createNamedWalletCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.createNamedWallet(*createNamedWalletName, nodeID)
+	}
+
+	if loadWalletCmd.Parsed() {
+		if *loadWalletName == "" {
+			loadWalletCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.loadWallet(*loadWalletName, nodeID)
+	}
+
+	if unloadWalletCmd.Parsed() {
+		if *unloadWalletName == "" {
+			unloadWalletCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.unloadWallet(*unloadWalletName, nodeID)
+	}
+
+	if listWalletsCmd.Parsed() {
+		cli.listWallets(nodeID)
+	}
+
 	if gbCmd.Parsed() {
 		if *getBalanceAddress == "" {
-			gbCmd.Usage()
+			cli.getWalletBalance(nodeID)
 			runtime.Goexit()
 		}
 		cli.getBalance(*getBalanceAddress, nodeID)
@@ -258,36 +847,329 @@ func (cli *CommandLine) Run() {
 	}
 
 	if sendCmd.Parsed() {
-		if *sendFrom == "" || *sendTo == "" || *sendAmount <= 0 {
+		if *sendFrom == "" || (len(sendTo) == 0 && *sendCSV == "") {
 			sendCmd.Usage()
 			runtime.Goexit()
 		}
 
-		cli.send(*sendFrom, *sendTo, *sendAmount, nodeID, *sendMine)
+		recipients, err := parseRecipients(sendTo, *sendAmount, *sendCSV)
+		if err != nil {
+			log.Panic(err)
+		}
+		recipients = resolveContacts(recipients, nodeID)
+
+		cli.send(*sendFrom, recipients, *sendChange, *sendNewChange, *sendCoinSelect, *sendInputs, *sendFee, *sendReplaceable, *sendUnconfirmed, nodeID, *sendMine)
 	}
 
 	if printChainCmd.Parsed() {
 		cli.printChain(nodeID)
 	}
 
+	if dumpPrivKeyCmd.Parsed() {
+		if *dumpPrivKeyAddress == "" {
+			dumpPrivKeyCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.dumpPrivKey(*dumpPrivKeyAddress, nodeID)
+	}
+
+	if importPrivKeyCmd.Parsed() {
+		if *importPrivKeyKey == "" {
+			importPrivKeyCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.importPrivKey(*importPrivKeyKey, *importPrivKeyRescan, nodeID)
+	}
+
+	if listTransactionsCmd.Parsed() {
+		cli.listTransactions(*listTransactionsAddress, *listTransactionsCategory, *listTransactionsMinConf, *listTransactionsCount, nodeID)
+	}
+
+	if rescanCmd.Parsed() {
+		if *rescanFromHeight < 0 {
+			rescanCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.rescan(*rescanFromHeight, nodeID)
+	}
+
+	if signMessageCmd.Parsed() {
+		if *signMessageAddress == "" {
+			signMessageCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.signMessage(*signMessageAddress, *signMessageMessage, nodeID)
+	}
+
+	if verifyMessageCmd.Parsed() {
+		if *verifyMessageAddress == "" || *verifyMessageSignature == "" {
+			verifyMessageCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.verifyMessage(*verifyMessageAddress, *verifyMessageSignature, *verifyMessageMessage)
+	}
+
+	if setLabelCmd.Parsed() {
+		if *setLabelAddress == "" {
+			setLabelCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.setLabel(*setLabelAddress, *setLabelLabel, nodeID)
+	}
+
+	if addContactCmd.Parsed() {
+		if *addContactName == "" || *addContactAddress == "" {
+			addContactCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.addContact(*addContactName, *addContactAddress, nodeID)
+	}
+
+	if removeContactCmd.Parsed() {
+		if *removeContactName == "" {
+			removeContactCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.removeContact(*removeContactName, nodeID)
+	}
+
+	if listContactsCmd.Parsed() {
+		cli.listContacts(nodeID)
+	}
+
+	if exportAddressBookCmd.Parsed() {
+		if *exportAddressBookOut == "" {
+			exportAddressBookCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.exportAddressBook(*exportAddressBookOut, nodeID)
+	}
+
+	if importAddressBookCmd.Parsed() {
+		if *importAddressBookIn == "" {
+			importAddressBookCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.importAddressBook(*importAddressBookIn, nodeID)
+	}
+
+	if importWatchCmd.Parsed() {
+		if (*importWatchAddress == "") == (*importWatchPubKey == "") {
+			importWatchCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.importWatch(*importWatchAddress, *importWatchPubKey, *importWatchType, nodeID)
+	}
+
 	if listAddressesCmd.Parsed() {
 		cli.listAddresses(nodeID)
 	}
 
 	if createWalletCmd.Parsed() {
-		cli.createWallet(nodeID)
+		cli.createWallet(nodeID, *createWalletType, *createWalletFormat)
 	}
 
 	if ReIndexUTXOCmd.Parsed() {
 		cli.ReIndexUTXO()
 	}
 
+	if createUnsignedCmd.Parsed() {
+		if *unsignedFrom == "" || *unsignedOut == "" || (len(unsignedTo) == 0 && *unsignedCSV == "") {
+			createUnsignedCmd.Usage()
+			runtime.Goexit()
+		}
+
+		recipients, err := parseRecipients(unsignedTo, *unsignedAmount, *unsignedCSV)
+		if err != nil {
+			log.Panic(err)
+		}
+		recipients = resolveContacts(recipients, nodeID)
+
+		cli.createUnsigned(*unsignedFrom, recipients, *unsignedChange, *unsignedCoinSelect, *unsignedInputs, *unsignedOut, nodeID)
+	}
+
+	if signOfflineCmd.Parsed() {
+		if *signOfflineIn == "" || *signOfflineOut == "" {
+			signOfflineCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.signOffline(*signOfflineIn, *signOfflineOut, nodeID)
+	}
+
+	if combineCmd.Parsed() {
+		if *combineIn == "" || *combineOut == "" {
+			combineCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.combine(strings.Split(*combineIn, ","), *combineOut)
+	}
+
+	if finalizeCmd.Parsed() {
+		if *finalizeIn == "" || (*finalizeMine && *finalizeMiner == "") {
+			finalizeCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.finalizeAndBroadcast(*finalizeIn, *finalizeMiner, nodeID, *finalizeMine)
+	}
+
+	if createRawTxCmd.Parsed() {
+		if *rawInputs == "" || (len(rawTo) == 0 && *rawData == "") {
+			createRawTxCmd.Usage()
+			runtime.Goexit()
+		}
+
+		var recipients []blockchain.Recipient
+		for _, entry := range rawTo {
+			r, err := blockchain.ParseRecipient(entry)
+			if err != nil {
+				log.Panic(err)
+			}
+			recipients = append(recipients, r)
+		}
+		cli.createRawTx(*rawInputs, resolveContacts(recipients, nodeID), *rawData)
+	}
+
+	if decodeRawTxCmd.Parsed() {
+		if *decodeRawTxHex == "" {
+			decodeRawTxCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.decodeRawTx(*decodeRawTxHex)
+	}
+
+	if signRawTxCmd.Parsed() {
+		if *signRawTxHex == "" {
+			signRawTxCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.signRawTx(*signRawTxHex, *signRawTxSigHash, nodeID)
+	}
+
+	if sendRawTxCmd.Parsed() {
+		if *sendRawTxHex == "" || (*sendRawTxMine && *sendRawTxMiner == "") {
+			sendRawTxCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.sendRawTx(*sendRawTxHex, *sendRawTxMiner, nodeID, *sendRawTxMine)
+	}
+
+	if bumpFeeCmd.Parsed() {
+		if *bumpFeeTxID == "" {
+			bumpFeeCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.bumpFee(*bumpFeeTxID, *bumpFeeFee, nodeID)
+	}
+
+	if importTxCmd.Parsed() {
+		if *importTxHex == "" {
+			importTxCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.importTx(*importTxHex, nodeID)
+	}
+
+	if createHDWalletCmd.Parsed() {
+		cli.createHDWallet(*createHDWalletType, *createHDWalletWords, *createHDWalletSeedPassphrase, nodeID)
+	}
+
+	if restoreHDWalletCmd.Parsed() {
+		if *restoreHDWalletMnemonic == "" {
+			restoreHDWalletCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.restoreHDWallet(*restoreHDWalletMnemonic, *restoreHDWalletType, *restoreHDWalletSeedPassphrase, *restoreHDWalletGap, nodeID)
+	}
+
+	if rescanWalletCmd.Parsed() {
+		cli.rescanWallet(*rescanWalletGap, nodeID)
+	}
+
+	if encryptWalletCmd.Parsed() {
+		if *encryptWalletPassphrase == "" {
+			*encryptWalletPassphrase = readPassphrase("New passphrase: ")
+		}
+		cli.encryptWallet(*encryptWalletPassphrase, nodeID)
+	}
+
+	if changePassphraseCmd.Parsed() {
+		if *changePassphraseOld == "" {
+			*changePassphraseOld = readPassphrase("Current passphrase: ")
+		}
+		if *changePassphraseNew == "" {
+			*changePassphraseNew = readPassphrase("New passphrase: ")
+		}
+		cli.changePassphrase(*changePassphraseOld, *changePassphraseNew, nodeID)
+	}
+
+	if unlockWalletCmd.Parsed() {
+		if *unlockWalletPassphrase == "" {
+			*unlockWalletPassphrase = readPassphrase("Passphrase: ")
+		}
+		cli.unlockWallet(*unlockWalletPassphrase, *unlockWalletTimeout, nodeID)
+	}
+
+	if lockWalletCmd.Parsed() {
+		cli.lockWallet(nodeID)
+	}
+
+	if unlockAgentCmd.Parsed() {
+		cli.unlockAgent(*unlockAgentTimeout, nodeID)
+	}
+
+	if listUnspentCmd.Parsed() {
+		if *listUnspentAddress == "" {
+			listUnspentCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.listUnspent(*listUnspentAddress, nodeID)
+	}
+
+	if consolidateCmd.Parsed() {
+		if *consolidateAddress == "" {
+			consolidateCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.consolidate(*consolidateAddress, *consolidateMaxInputs, *consolidateMinValue, *consolidateFee, nodeID, *consolidateMine)
+	}
+
+	if sweepCmd.Parsed() {
+		if *sweepFrom == "" || *sweepTo == "" {
+			sweepCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.sweep(*sweepFrom, *sweepTo, *sweepFee, nodeID, *sweepMine)
+	}
+
+	if anchorCmd.Parsed() {
+		if *anchorFile == "" || *anchorFrom == "" {
+			anchorCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.anchor(*anchorFile, *anchorFrom, nodeID, *anchorMine)
+	}
+
+	if verifyAnchorCmd.Parsed() {
+		if *verifyAnchorFile == "" {
+			verifyAnchorCmd.Usage()
+			runtime.Goexit()
+		}
+		cli.verifyAnchor(*verifyAnchorFile, nodeID)
+	}
+
 	if startNodeCmd.Parsed() {
 		nodeID := os.Getenv("NODE_ID")
 		if nodeID == "" {
 			startNodeCmd.Usage()
 			runtime.Goexit()
 		}
-		cli.StartNode(nodeID, *startNodeMiner)
+		policy := blockchain.Policy{
+			DustLimit:           *startNodeDustLimit,
+			MaxTxSize:           *startNodeMaxTxSize,
+			MaxSigOps:           *startNodeMaxSigOps,
+			MaxDataOutputs:      *startNodeMaxDataOutputs,
+			IncrementalRelayFee: *startNodeIncrementalRelayFee,
+		}
+		cli.StartNode(nodeID, *startNodeMiner, policy)
 	}
 }
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"blockchain/network"
+	"blockchain/wallet"
+	"fmt"
+	"log"
+)
+
+// consolidate 把地址上零碎的小額輸出合併成一個輸出
+func (cli *CommandLine) consolidate(address string, maxInputs, minValue, fee int, nodeID string, mineNow bool) {
+	cli.runConsolidator(address, "", maxInputs, minValue, fee, false, nodeID, mineNow)
+}
+
+// sweep 把地址上所有輸出轉到另一個地址
+func (cli *CommandLine) sweep(from, to string, fee int, nodeID string, mineNow bool) {
+	if !wallet.ValidateAddress(to) {
+		log.Panic("to addres is not valid ")
+	}
+	cli.runConsolidator(from, to, 0, 0, fee, true, nodeID, mineNow)
+}
+
+func (cli *CommandLine) runConsolidator(from, to string, maxInputs, minValue, fee int, all bool, nodeID string, mineNow bool) {
+	if !wallet.ValidateAddress(from) {
+		log.Panic("from addres is not valid ")
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+	UTXOSet := blockchain.UTXOSet{BlockChain: chain}
+
+	wallets := signingWallets(nodeID)
+	w, err := wallets.GetSigningWallet(from)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	c := blockchain.Consolidator{
+		Wallet:    &w,
+		UTXO:      &UTXOSet,
+		To:        to,
+		MaxInputs: maxInputs,
+		MinValue:  minValue,
+		Fee:       fee,
+	}
+
+	var txs []*blockchain.Transaction
+	if all {
+		txs, err = c.Sweep()
+	} else {
+		var tx *blockchain.Transaction
+		tx, err = c.Build()
+		txs = append(txs, tx)
+	}
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if mineNow {
+		block := mineBlock(chain, from, txs...)
+		UTXOSet.Update(block)
+	} else {
+		for _, tx := range txs {
+			network.SendTx(network.KnownNodes[0], tx)
+			addPending(nodeID, tx)
+		}
+		fmt.Println("send tx")
+	}
+
+	for _, tx := range txs {
+		fmt.Printf("Transaction %x spends %d input(s) into %d\n", tx.ID, len(tx.Inputs), tx.Outputs[0].Value)
+	}
+}
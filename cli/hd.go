@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"blockchain/wallet"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+)
+
+// hdWallets 載入錢包檔案, 檔案不存在時回傳空的錢包
+func hdWallets(nodeID string) *wallet.Wallets {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil && !os.IsNotExist(err) {
+		log.Panic(err)
+	}
+	if wallets.IsLocked() {
+		log.Panic(wallet.ErrWalletLocked)
+	}
+	return wallets
+}
+
+// rescanHD 以鏈上的輸出找回 HD 錢包用過的地址
+func rescanHD(chain *blockchain.BlockChain, wallets *wallet.Wallets, gapLimit int) int {
+	used := chain.UsedPubKeyHashes()
+	found, err := wallets.Rescan(func(pubKeyHash []byte) bool {
+		return used[hex.EncodeToString(pubKeyHash)]
+	}, gapLimit)
+	if err != nil {
+		log.Panic(err)
+	}
+	return found
+}
+
+// createHDWallet 產生助記詞並以其種子建立 HD 錢包
+func (cli *CommandLine) createHDWallet(keyType string, words int, seedPassphrase, nodeID string) {
+	t, err := wallet.ParseKeyType(keyType)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	mnemonic, err := wallet.NewMnemonic(words)
+	if err != nil {
+		log.Panic(err)
+	}
+	seed, err := wallet.MnemonicSeed(mnemonic, seedPassphrase)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallets := hdWallets(nodeID)
+	if err := wallets.InitHD(seed, t); err != nil {
+		log.Panic(err)
+	}
+	address := wallets.AddWallet(t)
+	wallets.SaveFile(nodeID)
+
+	fmt.Println("Write down the mnemonic, it restores every address of this wallet:")
+	fmt.Println(mnemonic)
+	fmt.Println("address is " + address)
+}
+
+// restoreHDWallet 由助記詞還原 HD 錢包, 並找回鏈上用過的地址
+func (cli *CommandLine) restoreHDWallet(mnemonic, keyType, seedPassphrase string, gapLimit int, nodeID string) {
+	t, err := wallet.ParseKeyType(keyType)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	seed, err := wallet.MnemonicSeed(mnemonic, seedPassphrase)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallets := hdWallets(nodeID)
+	if err := wallets.InitHD(seed, t); err != nil {
+		log.Panic(err)
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	found := rescanHD(chain, wallets, gapLimit)
+	if found == 0 {
+		wallets.AddWallet(t)
+	}
+	wallets.SaveFile(nodeID)
+	rescanHistory(chain, wallets, nodeID)
+
+	fmt.Printf("Restored HD wallet, %d used address(es) found\n", found)
+}
+
+// rescanWallet 重新搜尋 HD 錢包用過的地址, 例如從其他節點使用同一組助記詞之後
+func (cli *CommandLine) rescanWallet(gapLimit int, nodeID string) {
+	wallets := hdWallets(nodeID)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	found := rescanHD(chain, wallets, gapLimit)
+	wallets.SaveFile(nodeID)
+	rescanHistory(chain, wallets, nodeID)
+
+	fmt.Printf("%d used address(es) found\n", found)
+}
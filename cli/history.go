@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"blockchain/wallet"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+)
+
+// syncHistory 載入錢包交易紀錄並更新到目前的鏈頂
+func syncHistory(chain *blockchain.BlockChain, wallets *wallet.Wallets, nodeID string) *wallet.History {
+	h := wallet.LoadHistory(nodeID)
+	chain.SyncHistory(h, wallets, pendingTransactions(nodeID, chain))
+	h.Save(nodeID)
+	return h
+}
+
+// rescanHistory 錢包加入地址之後從創世區塊重新建立交易紀錄, 新地址過去的交易才會出現在紀錄中;
+// syncHistory 只連接紀錄鏈頂之後的區塊
+func rescanHistory(chain *blockchain.BlockChain, wallets *wallet.Wallets, nodeID string) {
+	h := wallet.LoadHistory(nodeID)
+	chain.RescanHistory(h, wallets, 0, pendingTransactions(nodeID, chain))
+	h.Save(nodeID)
+}
+
+// listTransactions 列出錢包交易紀錄, 可依地址, 分類與確認數篩選, count 大於 0 時只列出最新的幾筆
+func (cli *CommandLine) listTransactions(address, category string, minConf, count int, nodeID string) {
+	if address != "" {
+		normalized, err := wallet.NormalizeAddress(address)
+		if err != nil {
+			log.Panic(err)
+		}
+		address = normalized
+	}
+
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	h := syncHistory(chain, wallets, nodeID)
+	best := h.Height
+
+	var txs []*wallet.HistoryTx
+	for _, t := range h.Transactions() {
+		if address != "" && !t.HasAddress(address) {
+			continue
+		}
+		if category != "" && t.Category() != category {
+			continue
+		}
+		if t.Confirmations(best) < minConf {
+			continue
+		}
+		txs = append(txs, t)
+	}
+	if count > 0 && len(txs) > count {
+		txs = txs[len(txs)-count:]
+	}
+
+	for _, t := range txs {
+		when := "unconfirmed"
+		if t.Height >= 0 {
+			when = time.Unix(int64(t.Timestamp), 0).UTC().Format(time.RFC3339)
+		}
+		watch := ""
+		if t.WatchOnly {
+			watch = " (watch-only)"
+		}
+		fmt.Printf("%s %-8s amount %d fee %d change %d confirmations %d %s%s\n",
+			hex.EncodeToString(t.ID), t.Category(), t.Amount(), t.Fee, t.Change(), t.Confirmations(best), when, watch)
+	}
+	fmt.Printf("%d transaction(s)\n", len(txs))
+}
+
+// rescan 從指定高度重新建立錢包交易紀錄
+func (cli *CommandLine) rescan(fromHeight int, nodeID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	h := wallet.LoadHistory(nodeID)
+	chain.RescanHistory(h, wallets, fromHeight, pendingTransactions(nodeID, chain))
+	h.Save(nodeID)
+
+	fmt.Printf("Rescanned from height %d to %d, %d transaction(s) in the wallet\n", fromHeight, h.Height, len(h.Txs))
+}
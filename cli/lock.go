@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"blockchain/wallet"
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unlockAgentCommand 由 unlockWallet 在背景啟動的內部命令, 解鎖期間在記憶體中保存金鑰
+const unlockAgentCommand = "unlockAgent"
+
+// 代理程式在標準輸出的最後一行回報解鎖結果
+const (
+	agentReady = "unlocked"
+	agentError = "error: "
+)
+
+// stdin 多次讀取密碼時共用緩衝, 標準輸入是管線時才不會遺失後面的行
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassphrase 命令列沒有指定密碼時從標準輸入讀取一行, 避免密碼出現在 argv 與 shell 歷史中;
+// 標準輸入是終端機時顯示提示並關閉回顯
+func readPassphrase(prompt string) string {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, prompt)
+		if setEcho(false) == nil {
+			defer func() {
+				setEcho(true)
+				fmt.Fprintln(os.Stderr)
+			}()
+		}
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		log.Panic("fail to read passphrase: ", err)
+	}
+	passphrase := strings.TrimRight(line, "\r\n")
+	if passphrase == "" {
+		log.Panic("passphrase is empty")
+	}
+	return passphrase
+}
+
+func setEcho(on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	stty := exec.Command("stty", mode)
+	stty.Stdin = os.Stdin
+	return stty.Run()
+}
+
+// signingWallets 載入錢包, 需要私鑰的命令在錢包鎖定時拒絕執行
+func signingWallets(nodeID string) *wallet.Wallets {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+	if wallets.IsLocked() {
+		log.Panic(wallet.ErrWalletLocked)
+	}
+	return wallets
+}
+
+func (cli *CommandLine) encryptWallet(passphrase, nodeID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+	if wallets.IsEncrypted() {
+		log.Panic("wallet is already encrypted, use changePassphrase")
+	}
+
+	if err := wallets.Encrypt(passphrase); err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Println("Wallet encrypted, run unlockWallet before signing")
+}
+
+func (cli *CommandLine) changePassphrase(oldPassphrase, newPassphrase, nodeID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if err := wallets.ChangePassphrase(oldPassphrase, newPassphrase); err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveFile(nodeID)
+	// 舊的解鎖紀錄使用舊金鑰, 已經無法解密
+	if err := wallet.Lock(nodeID); err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Println("Passphrase changed")
+}
+
+func (cli *CommandLine) unlockWallet(passphrase string, timeout int, nodeID string) {
+	if timeout <= 0 {
+		log.Panic("timeout must be positive")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// 密碼經由標準輸入傳給代理程式, 不出現在其他程序可見的參數中
+	agent := exec.Command(exe, unlockAgentCommand, "-wallet", wallet.ActiveWalletName(), "-timeout", strconv.Itoa(timeout))
+	agent.Stdin = strings.NewReader(passphrase + "\n")
+	out, err := agent.StdoutPipe()
+	if err != nil {
+		log.Panic(err)
+	}
+	if err := agent.Start(); err != nil {
+		log.Panic(err)
+	}
+
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == agentReady {
+			agent.Process.Release()
+			fmt.Printf("Wallet unlocked for %d seconds\n", timeout)
+			return
+		}
+		if strings.HasPrefix(line, agentError) {
+			agent.Wait()
+			log.Panic(strings.TrimPrefix(line, agentError))
+		}
+	}
+	agent.Wait()
+	log.Panic("unlock agent exited before unlocking the wallet")
+}
+
+// unlockAgent 以標準輸入的密碼解鎖錢包, 在 timeout 內提供金鑰給其他命令, 到期後清除並結束
+func (cli *CommandLine) unlockAgent(timeout int, nodeID string) {
+	passphrase, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		fmt.Println(agentError + err.Error())
+		return
+	}
+
+	session, err := wallet.NewSession(nodeID, strings.TrimSuffix(passphrase, "\n"))
+	if err != nil {
+		fmt.Println(agentError + err.Error())
+		return
+	}
+	fmt.Println(agentReady)
+
+	session.Serve(time.Duration(timeout) * time.Second)
+}
+
+func (cli *CommandLine) lockWallet(nodeID string) {
+	if err := wallet.Lock(nodeID); err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Println("Wallet locked")
+}
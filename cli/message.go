@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"fmt"
+	"log"
+)
+
+// signMessage 以地址的私鑰簽署訊息, 證明擁有此地址而不需要轉帳
+func (cli *CommandLine) signMessage(address, message, nodeID string) {
+	wallets := signingWallets(nodeID)
+
+	w, err := wallets.GetSigningWallet(address)
+	if err != nil {
+		log.Panic(err)
+	}
+	signature, err := blockchain.SignMessage(&w, message)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Println(signature)
+}
+
+// verifyMessage 只需要地址, 簽章與訊息, 不需要錢包或區塊鏈
+func (cli *CommandLine) verifyMessage(address, signature, message string) {
+	if err := blockchain.VerifyMessage(address, signature, message); err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Println("Signature is valid")
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"blockchain/wallet"
+	"fmt"
+	"log"
+)
+
+func (cli *CommandLine) createNamedWallet(name, nodeID string) {
+	if err := wallet.CreateNamedWallet(nodeID, name); err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("Wallet %s created and loaded, add addresses with createWallet -wallet %s\n", name, name)
+}
+
+func (cli *CommandLine) loadWallet(name, nodeID string) {
+	if err := wallet.LoadWallet(nodeID, name); err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("Wallet %s loaded\n", name)
+}
+
+// unloadWallet 卸載後 -wallet 無法再選擇此錢包, 直到重新載入
+func (cli *CommandLine) unloadWallet(name, nodeID string) {
+	if err := wallet.UnloadWallet(nodeID, name); err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("Wallet %s unloaded\n", name)
+}
+
+func (cli *CommandLine) listWallets(nodeID string) {
+	wallets, err := wallet.ListWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Println("(default) loaded")
+	for _, w := range wallets {
+		state := "unloaded"
+		if w.Loaded {
+			state = "loaded"
+		}
+		fmt.Printf("%s %s\n", w.Name, state)
+	}
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"blockchain/network"
+	"blockchain/wallet"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// 部分簽署交易以 hex 文字檔在機器之間傳遞
+
+func writePartial(path string, p *blockchain.PartialTransaction) {
+	content := hex.EncodeToString(p.Serialize()) + "\n"
+
+	// 檔案含有輸入與金額, 與錢包檔案一樣只有擁有者可以讀寫
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		log.Panic(err)
+	}
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+func readPartial(path string) *blockchain.PartialTransaction {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	data, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	p, err := blockchain.DecodePartialTransaction(data)
+	if err != nil {
+		log.Panic(err)
+	}
+	return p
+}
+
+// createUnsigned 在連線的節點上以寄件地址建立未簽署交易, 不需要私鑰
+func (cli *CommandLine) createUnsigned(from string, recipients []blockchain.Recipient, change, coinSelect, inputs, out, nodeID string) {
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+	UTXOSet := blockchain.UTXOSet{BlockChain: chain}
+
+	selector, outpoints := parseCoinOptions(coinSelect, inputs)
+
+	builder := blockchain.TxBuilder{
+		From:          from,
+		UTXO:          &UTXOSet,
+		Recipients:    recipients,
+		ChangeAddress: change,
+		Selector:      selector,
+		Inputs:        outpoints,
+	}
+	p, err := builder.BuildUnsigned()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	writePartial(out, p)
+	fmt.Printf("Unsigned transaction %x written to %s\n", p.Tx.ID, out)
+}
+
+// signOffline 只需要錢包檔案, 以錢包中所有符合的金鑰簽署
+func (cli *CommandLine) signOffline(in, out, nodeID string) {
+	p := readPartial(in)
+
+	wallets := signingWallets(nodeID)
+	printPartialSummary(p, wallets)
+
+	signed := 0
+	for _, address := range wallets.SigningAddresses() {
+		w := wallets.GetWallet(address)
+		n, err := p.Sign(&w, blockchain.SigHashAll)
+		if err != nil {
+			log.Panic(err)
+		}
+		signed += n
+	}
+
+	writePartial(out, p)
+	fmt.Printf("Signed %d input(s), complete: %t\n", signed, p.IsComplete())
+}
+
+// printPartialSummary 簽署前列出每個輸出的金額與收款地址, 以及手續費; 輸出總額超過輸入時拒絕簽署
+func printPartialSummary(p *blockchain.PartialTransaction, wallets *wallet.Wallets) {
+	owned := walletKeyHashes(wallets, wallets.GetAllAddresses())
+
+	input := 0
+	for _, prevOut := range p.PrevOuts {
+		input += prevOut.Value
+	}
+	fmt.Printf("Transaction %x spends %d from %d input(s)\n", p.Tx.ID, input, len(p.PrevOuts))
+
+	for i, out := range p.Tx.Outputs {
+		switch {
+		case out.IsData():
+			fmt.Printf("  output %d: data %x\n", i, out.Data)
+		case owned[hex.EncodeToString(out.PubKeyHash)]:
+			fmt.Printf("  output %d: %d to %s (this wallet)\n", i, out.Value, wallet.EncodeAddress(out.Type, out.PubKeyHash))
+		default:
+			fmt.Printf("  output %d: %d to %s\n", i, out.Value, wallet.EncodeAddress(out.Type, out.PubKeyHash))
+		}
+	}
+
+	fee := p.Tx.Fee(p.PrevOuts)
+	if fee < 0 {
+		log.Panicf("outputs exceed inputs by %d, refusing to sign", -fee)
+	}
+	fmt.Printf("  fee: %d\n", fee)
+}
+
+// combine 合併多個簽署者各自簽署的檔案
+func (cli *CommandLine) combine(ins []string, out string) {
+	p := readPartial(ins[0])
+
+	for _, in := range ins[1:] {
+		if err := p.Combine(readPartial(in)); err != nil {
+			log.Panic(err)
+		}
+	}
+
+	writePartial(out, p)
+	fmt.Printf("Combined %d file(s), complete: %t\n", len(ins), p.IsComplete())
+}
+
+// finalizeAndBroadcast 組出完整交易, 以區塊鏈驗證後廣播或直接挖礦
+func (cli *CommandLine) finalizeAndBroadcast(in, miner, nodeID string, mineNow bool) {
+	p := readPartial(in)
+
+	tx, err := p.Finalize()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+	UTXOSet := blockchain.UTXOSet{BlockChain: chain}
+
+	if !chain.VerifyTransaction(tx) {
+		log.Panic("transaction does not verify against the chain")
+	}
+
+	if mineNow {
+		if !wallet.ValidateAddress(miner) {
+			log.Panic("miner address is not valid")
+		}
+		block := mineBlock(chain, miner, tx)
+		UTXOSet.Update(block)
+	} else {
+		network.SendTx(network.KnownNodes[0], tx)
+		fmt.Println("send tx")
+	}
+
+	fmt.Printf("Transaction %x broadcast\n", tx.ID)
+}
@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"blockchain/network"
+	"blockchain/params"
+	"blockchain/wallet"
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+// pendingFile 記錄本節點送出或收到但尚未確認的交易, bumpFee 由此取得原交易,
+// send -unconfirmed 可以花費其中付給錢包的輸出
+const pendingFile = "./tmp/pending_%s.data"
+
+func loadPending(nodeID string) map[string][]byte {
+	pending := make(map[string][]byte)
+
+	content, err := ioutil.ReadFile(params.Active.Path(pendingFile, nodeID))
+	if os.IsNotExist(err) {
+		return pending
+	}
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = gob.NewDecoder(bytes.NewReader(content)).Decode(&pending)
+	if err != nil {
+		log.Panic(err)
+	}
+	return pending
+}
+
+func savePending(nodeID string, pending map[string][]byte) {
+	var content bytes.Buffer
+
+	err := gob.NewEncoder(&content).Encode(pending)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// 之前的版本以 0644 建立檔案, WriteFile 不會改變已存在檔案的權限
+	path := params.Active.Path(pendingFile, nodeID)
+	err = ioutil.WriteFile(path, content.Bytes(), 0600)
+	if err != nil {
+		log.Panic(err)
+	}
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+func addPending(nodeID string, tx *blockchain.Transaction) {
+	pending := loadPending(nodeID)
+	pending[hex.EncodeToString(tx.ID)] = tx.Serialize()
+	savePending(nodeID, pending)
+}
+
+// pendingTransactions 解碼尚未確認的交易, 已經進入區塊的交易會從紀錄中移除
+func pendingTransactions(nodeID string, chain *blockchain.BlockChain) map[string]blockchain.Transaction {
+	pending := loadPending(nodeID)
+	txs := make(map[string]blockchain.Transaction)
+	confirmed := false
+
+	for id, data := range pending {
+		tx, err := blockchain.DecodeTransaction(data)
+		if err != nil {
+			log.Panic(err)
+		}
+		if _, err := chain.FindTransaction(tx.ID); err == nil {
+			delete(pending, id)
+			confirmed = true
+			continue
+		}
+		txs[id] = tx
+	}
+
+	if confirmed {
+		savePending(nodeID, pending)
+	}
+	return txs
+}
+
+// importTx 記錄一筆付給錢包但尚未確認的交易, 讓收款者可以在確認前花費它 (child pays for parent)
+func (cli *CommandLine) importTx(rawHex, nodeID string) {
+	tx := decodeRawTx(rawHex)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	if !chain.VerifyTransactionsWith([]*blockchain.Transaction{&tx}, pendingTransactions(nodeID, chain)) {
+		log.Panic("transaction does not verify against the chain and pending transactions")
+	}
+
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	owned := walletKeyHashes(wallets, wallets.GetAllAddresses())
+	paid := 0
+	for _, out := range tx.Outputs {
+		if !out.IsData() && owned[hex.EncodeToString(out.PubKeyHash)] {
+			paid++
+		}
+	}
+	if paid == 0 {
+		log.Panic("transaction does not pay any address in the wallet")
+	}
+
+	addPending(nodeID, &tx)
+	fmt.Printf("Imported %x, %d output(s) pay this wallet\n", tx.ID, paid)
+}
+
+// bumpFee 以更高的手續費重新簽署一筆可取代的交易, 差額由找零輸出支付
+func (cli *CommandLine) bumpFee(txID string, fee int, nodeID string) {
+	pending := loadPending(nodeID)
+	data, ok := pending[txID]
+	if !ok {
+		log.Panicf("transaction %s is not a pending transaction of this wallet", txID)
+	}
+
+	tx, err := blockchain.DecodeTransaction(data)
+	if err != nil {
+		log.Panic(err)
+	}
+	if !tx.IsReplaceable() {
+		log.Panic("transaction does not signal replaceability")
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	// 原交易可能花費其他尚未確認的交易
+	unconfirmed := pendingTransactions(nodeID, chain)
+	prevOuts, err := chain.PrevOutputsWith(&tx, unconfirmed)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// 取代後花費原交易的待確認交易也會被移除, 新的手續費必須超過它們與原交易的總和
+	oldFee := tx.Fee(prevOuts)
+	descendants := pendingDescendants(txID, unconfirmed)
+	replacedFee := oldFee
+	for _, id := range descendants {
+		descendant := unconfirmed[id]
+		outs, err := chain.PrevOutputsWith(&descendant, unconfirmed)
+		if err != nil {
+			log.Panic(err)
+		}
+		replacedFee += descendant.Fee(outs)
+	}
+
+	minFee := replacedFee + blockchain.DefaultIncrementalRelayFee
+	if fee <= 0 {
+		fee = minFee
+	}
+	if fee < minFee {
+		log.Panicf("new fee %d must be at least %d: replaced fees %d plus incremental relay fee %d", fee, minFee, replacedFee, blockchain.DefaultIncrementalRelayFee)
+	}
+
+	wallets := signingWallets(nodeID)
+
+	change := findChangeOutput(&tx, wallets)
+	if change < 0 {
+		log.Panic("transaction has no change output to pay the higher fee")
+	}
+
+	delta := fee - oldFee
+	if tx.Outputs[change].Value < delta {
+		log.Panicf("change %d cannot cover the fee increase %d", tx.Outputs[change].Value, delta)
+	}
+	tx.Outputs[change].Value -= delta
+	if tx.Outputs[change].Value == 0 {
+		tx.Outputs = append(tx.Outputs[:change], tx.Outputs[change+1:]...)
+	}
+
+	for inID := range tx.Inputs {
+		tx.Inputs[inID].Signature = nil
+	}
+	signWithWallets(&tx, prevOuts, wallets, blockchain.SigHashAll)
+	tx.SetID()
+
+	if !tx.VerifyWithPrevOuts(prevOuts) {
+		log.Panic("wallet could not sign every input of the replacement")
+	}
+
+	network.SendTx(network.KnownNodes[0], &tx)
+
+	delete(pending, txID)
+	for _, id := range descendants {
+		delete(pending, id)
+	}
+	pending[hex.EncodeToString(tx.ID)] = tx.Serialize()
+	savePending(nodeID, pending)
+
+	fmt.Printf("Replaced %s with %x, fee %d -> %d\n", txID, tx.ID, oldFee, fee)
+}
+
+// pendingDescendants 直接或間接花費 txID 輸出的待確認交易
+func pendingDescendants(txID string, unconfirmed map[string]blockchain.Transaction) []string {
+	var descendants []string
+	spent := map[string]bool{txID: true}
+
+	for found := true; found; {
+		found = false
+		for id, tx := range unconfirmed {
+			if spent[id] {
+				continue
+			}
+			for _, in := range tx.Inputs {
+				if spent[hex.EncodeToString(in.ID)] {
+					spent[id] = true
+					descendants = append(descendants, id)
+					found = true
+					break
+				}
+			}
+		}
+	}
+	return descendants
+}
+
+// walletKeyHashes 錢包中地址的公鑰雜湊 (hex)
+func walletKeyHashes(wallets *wallet.Wallets, addresses []string) map[string]bool {
+	owned := make(map[string]bool)
+	for _, address := range addresses {
+		w := wallets.GetWallet(address)
+		owned[hex.EncodeToString(wallet.PublicKeyHash(w.Publickey))] = true
+	}
+	return owned
+}
+
+// findChangeOutput 最後一個屬於錢包且有私鑰的輸出視為找零
+func findChangeOutput(tx *blockchain.Transaction, wallets *wallet.Wallets) int {
+	owned := walletKeyHashes(wallets, wallets.SigningAddresses())
+
+	for i := len(tx.Outputs) - 1; i >= 0; i-- {
+		out := tx.Outputs[i]
+		if !out.IsData() && owned[hex.EncodeToString(out.PubKeyHash)] {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"blockchain/wallet"
+	"fmt"
+	"log"
+)
+
+// dumpPrivKey 匯出地址的私鑰, 任何取得此字串的人都能花費地址上的輸出
+func (cli *CommandLine) dumpPrivKey(address, nodeID string) {
+	wallets := signingWallets(nodeID)
+
+	w, err := wallets.GetSigningWallet(address)
+	if err != nil {
+		log.Panic(err)
+	}
+	key, err := wallet.EncodePrivateKey(w)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Println(key)
+}
+
+// importPrivKey 匯入私鑰; rescan 時列出 UTXO set 中屬於此金鑰的輸出並重新建立交易紀錄
+func (cli *CommandLine) importPrivKey(key string, rescan bool, nodeID string) {
+	wallets := hdWallets(nodeID)
+
+	address, err := wallets.ImportPrivateKey(key)
+	if err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Println("address is " + address)
+
+	if rescan {
+		chain := blockchain.ContinueBlockChain(nodeID)
+		defer chain.Database.Close()
+		UTXOSet := blockchain.UTXOSet{BlockChain: chain}
+
+		_, pubKeyHash, err := wallet.DecodeAddress(address)
+		if err != nil {
+			log.Panic(err)
+		}
+		coins := UTXOSet.FindCoins(pubKeyHash)
+		balance := 0
+		for _, coin := range coins {
+			balance += coin.Output.Value
+		}
+		fmt.Printf("Found %d unspent output(s) worth %d\n", len(coins), balance)
+
+		rescanHistory(chain, wallets, nodeID)
+	}
+}
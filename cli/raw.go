@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"blockchain/network"
+	"blockchain/wallet"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+func decodeRawTx(rawHex string) blockchain.Transaction {
+	data, err := hex.DecodeString(strings.TrimSpace(rawHex))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	tx, err := blockchain.DecodeTransaction(data)
+	if err != nil {
+		log.Panic(err)
+	}
+	return tx
+}
+
+// createRawTx 以指定的輸入與輸出建立未簽署交易, 不檢查 UTXO set 也不產生找零
+func (cli *CommandLine) createRawTx(inputs string, recipients []blockchain.Recipient, dataHex string) {
+	var tx blockchain.Transaction
+
+	for _, entry := range strings.Split(inputs, ",") {
+		op, err := blockchain.ParseOutpoint(entry)
+		if err != nil {
+			log.Panic(err)
+		}
+		tx.Inputs = append(tx.Inputs, blockchain.TxInput{ID: op.TxID, Out: op.Index, Sequence: blockchain.MaxSequence})
+	}
+
+	for _, r := range recipients {
+		if r.Amount <= 0 || !wallet.ValidateAddress(r.Address) {
+			log.Panicf("recipient %s:%d is not valid", r.Address, r.Amount)
+		}
+		tx.Outputs = append(tx.Outputs, *blockchain.NewTXOutput(r.Amount, r.Address))
+	}
+
+	if dataHex != "" {
+		data, err := hex.DecodeString(dataHex)
+		if err != nil {
+			log.Panic(err)
+		}
+		out, err := blockchain.NewDataOutput(data)
+		if err != nil {
+			log.Panic(err)
+		}
+		tx.Outputs = append(tx.Outputs, *out)
+	}
+
+	tx.SetID()
+	fmt.Println(hex.EncodeToString(tx.Serialize()))
+}
+
+// decodeRawTxCmd 以 JSON 印出交易內容
+func (cli *CommandLine) decodeRawTx(rawHex string) {
+	tx := decodeRawTx(rawHex)
+
+	out, err := json.MarshalIndent(tx.View(), "", "  ")
+	if err != nil {
+		log.Panic(err)
+	}
+	fmt.Println(string(out))
+}
+
+// signWithWallets 以錢包中的金鑰簽署屬於它們的輸入, 回傳簽署的輸入數量
+func signWithWallets(tx *blockchain.Transaction, prevOuts []blockchain.TxOutput, wallets *wallet.Wallets, hashType byte) int {
+	signed := 0
+	for _, address := range wallets.SigningAddresses() {
+		w := wallets.GetWallet(address)
+		pubKeyHash := wallet.PublicKeyHash(w.Publickey)
+
+		for inID, prevOut := range prevOuts {
+			if !prevOut.IsLockedWithKey(pubKeyHash) {
+				continue
+			}
+
+			tx.Inputs[inID].PubKey = w.Publickey
+			if err := tx.SignInput(inID, w.PrivateKey, prevOut, hashType); err != nil {
+				log.Panic(err)
+			}
+			signed++
+		}
+	}
+
+	return signed
+}
+
+// signRawTx 以錢包中的金鑰簽署屬於它們的輸入, 其他輸入保持不變
+func (cli *CommandLine) signRawTx(rawHex, sigHash, nodeID string) {
+	tx := decodeRawTx(rawHex)
+
+	hashType, err := blockchain.ParseSigHashType(sigHash)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	prevOuts, err := chain.PrevOutputsWith(&tx, pendingTransactions(nodeID, chain))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallets := signingWallets(nodeID)
+
+	signed := signWithWallets(&tx, prevOuts, wallets, hashType)
+
+	tx.SetID()
+	fmt.Printf("signed %d of %d input(s), complete: %t\n", signed, len(tx.Inputs), tx.VerifyWithPrevOuts(prevOuts))
+	fmt.Println(hex.EncodeToString(tx.Serialize()))
+}
+
+// sendRawTx 驗證後廣播或直接挖礦
+func (cli *CommandLine) sendRawTx(rawHex, miner, nodeID string, mineNow bool) {
+	tx := decodeRawTx(rawHex)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+	UTXOSet := blockchain.UTXOSet{BlockChain: chain}
+
+	if !chain.VerifyTransactionsWith([]*blockchain.Transaction{&tx}, pendingTransactions(nodeID, chain)) {
+		log.Panic("transaction does not verify against the chain and pending transactions")
+	}
+
+	if mineNow {
+		if !wallet.ValidateAddress(miner) {
+			log.Panic("miner address is not valid")
+		}
+		block := mineBlock(chain, miner, &tx)
+		UTXOSet.Update(block)
+	} else {
+		network.SendTx(network.KnownNodes[0], &tx)
+		fmt.Println("send tx")
+	}
+
+	fmt.Printf("Transaction %x broadcast\n", tx.ID)
+}
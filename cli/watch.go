@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"blockchain/blockchain"
+	"blockchain/wallet"
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+// importWatch 加入觀察地址或公鑰, 不需要私鑰, 並重新掃描交易紀錄; 加密的錢包需先解鎖
+func (cli *CommandLine) importWatch(address, pubKeyHex, keyType, nodeID string) {
+	wallets := hdWallets(nodeID)
+
+	if pubKeyHex != "" {
+		t, err := wallet.ParseKeyType(keyType)
+		if err != nil {
+			log.Panic(err)
+		}
+		pub, err := hex.DecodeString(pubKeyHex)
+		if err != nil {
+			log.Panic(err)
+		}
+		if address, err = wallets.ImportPublicKey(t, pub); err != nil {
+			log.Panic(err)
+		}
+	} else if err := wallets.ImportAddress(address); err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveFile(nodeID)
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+	rescanHistory(chain, wallets, nodeID)
+
+	fmt.Printf("watching %s\n", address)
+}
+
+// getWalletBalance 由錢包交易紀錄列出每個地址已確認的餘額, 觀察地址另外標示
+func (cli *CommandLine) getWalletBalance(nodeID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	balances := syncHistory(chain, wallets, nodeID).Balances()
+
+	total, watched := 0, 0
+	for _, address := range wallets.WatchedAddresses() {
+		balance := balances[address]
+
+		name := address
+		if label := wallets.Label(address); label != "" {
+			name += " (" + label + ")"
+		}
+		if wallets.IsWatched(address) {
+			watched += balance
+			fmt.Printf("Balance of %s: %d (watch-only)\n", name, balance)
+		} else {
+			total += balance
+			fmt.Printf("Balance of %s: %d\n", name, balance)
+		}
+	}
+
+	fmt.Printf("Total: %d, watch-only: %d\n", total, watched)
+}
@@ -0,0 +1,346 @@
+package network
+
+import (
+	"blockchain/blockchain"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// maxReplacementEvictions 一次取代最多可移除的交易數 (含子孫交易)
+	maxReplacementEvictions = 100
+
+	// maxAncestors 與 maxDescendants 限制未確認交易鏈的長度 (含交易本身)
+	maxAncestors   = 25
+	maxDescendants = 25
+)
+
+// mempoolEntry 尚未確認的交易與其手續費資訊
+type mempoolEntry struct {
+	Tx   blockchain.Transaction
+	Fee  int
+	Size int
+}
+
+// Mempool 尚未確認的交易, 同一個輸出只能被一筆交易花費
+type Mempool struct {
+	Policy blockchain.Policy // 只套用在 mempool, 區塊中的交易不受限制
+
+	mu      sync.Mutex
+	entries map[string]*mempoolEntry
+	spends  map[string]string // "txid:index" -> 花費它的交易
+}
+
+// NewMempool ...
+func NewMempool() *Mempool {
+	return &Mempool{
+		Policy:  blockchain.DefaultPolicy(),
+		entries: make(map[string]*mempoolEntry),
+		spends:  make(map[string]string),
+	}
+}
+
+func outpointKey(txID []byte, index int) string {
+	return fmt.Sprintf("%x:%d", txID, index)
+}
+
+// Get ...
+func (mp *Mempool) Get(txID string) (blockchain.Transaction, bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	entry, ok := mp.entries[txID]
+	if !ok {
+		return blockchain.Transaction{}, false
+	}
+	return entry.Tx, true
+}
+
+// Len ...
+func (mp *Mempool) Len() int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	return len(mp.entries)
+}
+
+// Transactions ...
+func (mp *Mempool) Transactions() []blockchain.Transaction {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var txs []blockchain.Transaction
+	for _, entry := range mp.entries {
+		txs = append(txs, entry.Tx)
+	}
+	return txs
+}
+
+// Add 驗證交易並放入 mempool, 輸入可以花費鏈上或 mempool 中的輸出;
+// 與既有交易衝突時依 replace-by-fee 規則決定是否取代
+func (mp *Mempool) Add(tx blockchain.Transaction, chain *blockchain.BlockChain) error {
+	txID := hex.EncodeToString(tx.ID)
+
+	if err := mp.Policy.CheckStandard(&tx); err != nil {
+		return fmt.Errorf("non-standard transaction: %s", err)
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if _, ok := mp.entries[txID]; ok {
+		return errors.New("transaction is already in the mempool")
+	}
+
+	parents := mp.parents(&tx)
+	if !chain.VerifyTransactionsWith([]*blockchain.Transaction{&tx}, parents) {
+		return errors.New("transaction does not verify")
+	}
+
+	prevOuts, err := chain.PrevOutputsWith(&tx, parents)
+	if err != nil {
+		return err
+	}
+	entry := &mempoolEntry{tx, tx.Fee(prevOuts), tx.Size()}
+	if entry.Fee < 0 {
+		return errors.New("transaction spends more than its inputs")
+	}
+
+	ancestors := mp.ancestors(&tx)
+	if len(ancestors)+1 > maxAncestors {
+		return fmt.Errorf("transaction has too many unconfirmed ancestors (%d)", len(ancestors))
+	}
+	for _, id := range ancestors {
+		if len(mp.descendants(id))+1 > maxDescendants {
+			return fmt.Errorf("unconfirmed ancestor %s has too many descendants", id)
+		}
+	}
+
+	conflicts := make(map[string]bool)
+	for _, in := range tx.Inputs {
+		if spender, ok := mp.spends[outpointKey(in.ID, in.Out)]; ok {
+			conflicts[spender] = true
+		}
+	}
+
+	if len(conflicts) > 0 {
+		if err := mp.checkReplacement(entry, conflicts, ancestors); err != nil {
+			return err
+		}
+		for conflict := range conflicts {
+			mp.removeWithDescendants(conflict)
+		}
+	}
+
+	mp.entries[txID] = entry
+	for _, in := range tx.Inputs {
+		mp.spends[outpointKey(in.ID, in.Out)] = txID
+	}
+
+	return nil
+}
+
+// parents 交易輸入所引用, 仍在 mempool 中的交易
+func (mp *Mempool) parents(tx *blockchain.Transaction) map[string]blockchain.Transaction {
+	parents := make(map[string]blockchain.Transaction)
+	for _, in := range tx.Inputs {
+		id := hex.EncodeToString(in.ID)
+		if entry, ok := mp.entries[id]; ok {
+			parents[id] = entry.Tx
+		}
+	}
+	return parents
+}
+
+// ancestors 回傳交易在 mempool 中所有的祖先交易, 不含交易本身
+func (mp *Mempool) ancestors(tx *blockchain.Transaction) []string {
+	var result []string
+	seen := make(map[string]bool)
+
+	queue := []*blockchain.Transaction{tx}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for id, parent := range mp.parents(current) {
+			if !seen[id] {
+				seen[id] = true
+				result = append(result, id)
+				parent := parent
+				queue = append(queue, &parent)
+			}
+		}
+	}
+
+	return result
+}
+
+// packageOf 回傳 txID 以及尚未選入區塊的祖先, 父交易排在子交易之前
+func (mp *Mempool) packageOf(txID string, included map[string]bool) []string {
+	var pkg []string
+	seen := make(map[string]bool)
+
+	var visit func(id string)
+	visit = func(id string) {
+		if seen[id] || included[id] {
+			return
+		}
+		seen[id] = true
+		for _, in := range mp.entries[id].Tx.Inputs {
+			parent := hex.EncodeToString(in.ID)
+			if _, ok := mp.entries[parent]; ok {
+				visit(parent)
+			}
+		}
+		pkg = append(pkg, id)
+	}
+	visit(txID)
+
+	return pkg
+}
+
+// BlockTemplate 依套件 (交易加上尚未選入的祖先) 的合併手續費率由高到低選入交易, 直到總大小達到 maxSize;
+// 手續費高的子交易會帶著手續費低的父交易一起被選入 (child pays for parent)
+func (mp *Mempool) BlockTemplate(maxSize int) []blockchain.Transaction {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var txs []blockchain.Transaction
+	size := 0
+	included := make(map[string]bool)
+	// 放不下的交易與其子孫都不再考慮
+	skipped := make(map[string]bool)
+
+	for {
+		var (
+			best              []string
+			bestID            string
+			bestFee, bestSize int
+		)
+
+		for id := range mp.entries {
+			if included[id] || skipped[id] {
+				continue
+			}
+
+			pkg := mp.packageOf(id, included)
+			fee, pkgSize := 0, 0
+			for _, pid := range pkg {
+				fee += mp.entries[pid].Fee
+				pkgSize += mp.entries[pid].Size
+			}
+			if size+pkgSize > maxSize {
+				skipped[id] = true
+				continue
+			}
+
+			// 以交叉相乘比較手續費率, 相同時依 txid 排序讓結果固定
+			if best == nil || fee*bestSize > bestFee*pkgSize || (fee*bestSize == bestFee*pkgSize && id < bestID) {
+				best, bestID, bestFee, bestSize = pkg, id, fee, pkgSize
+			}
+		}
+
+		if best == nil {
+			return txs
+		}
+
+		for _, id := range best {
+			included[id] = true
+			txs = append(txs, mp.entries[id].Tx)
+		}
+		size += bestSize
+	}
+}
+
+// checkReplacement 取代規則: 被取代的交易都必須發出可取代訊號,
+// 新交易的手續費至少為所有被移除交易 (含子孫) 的總和加上 incremental relay fee, 手續費率也必須高於每筆直接衝突的交易,
+// 且新交易不能花費會被移除的交易
+func (mp *Mempool) checkReplacement(entry *mempoolEntry, conflicts map[string]bool, ancestors []string) error {
+	evicted := make(map[string]bool)
+	evictedFee := 0
+
+	for conflict := range conflicts {
+		old := mp.entries[conflict]
+		if !old.Tx.IsReplaceable() {
+			return fmt.Errorf("conflicting transaction %s is not replaceable", conflict)
+		}
+		if entry.Fee*old.Size <= old.Fee*entry.Size {
+			return fmt.Errorf("feerate does not exceed conflicting transaction %s", conflict)
+		}
+
+		for _, id := range mp.descendants(conflict) {
+			if !evicted[id] {
+				evicted[id] = true
+				evictedFee += mp.entries[id].Fee
+			}
+		}
+	}
+
+	for _, id := range ancestors {
+		if evicted[id] {
+			return fmt.Errorf("replacement spends transaction %s that it would evict", id)
+		}
+	}
+
+	if len(evicted) > maxReplacementEvictions {
+		return fmt.Errorf("replacement would evict %d transactions", len(evicted))
+	}
+	if entry.Fee < evictedFee+mp.Policy.IncrementalRelayFee {
+		return fmt.Errorf("fee %d does not exceed replaced fees %d by the incremental relay fee %d", entry.Fee, evictedFee, mp.Policy.IncrementalRelayFee)
+	}
+
+	return nil
+}
+
+// descendants 回傳 txID 以及所有花費其輸出的 mempool 交易
+func (mp *Mempool) descendants(txID string) []string {
+	result := []string{txID}
+	seen := map[string]bool{txID: true}
+
+	for i := 0; i < len(result); i++ {
+		entry := mp.entries[result[i]]
+		id, _ := hex.DecodeString(result[i])
+		for index := range entry.Tx.Outputs {
+			if child, ok := mp.spends[outpointKey(id, index)]; ok && !seen[child] {
+				seen[child] = true
+				result = append(result, child)
+			}
+		}
+	}
+
+	return result
+}
+
+func (mp *Mempool) remove(txID string) {
+	entry, ok := mp.entries[txID]
+	if !ok {
+		return
+	}
+
+	for _, in := range entry.Tx.Inputs {
+		key := outpointKey(in.ID, in.Out)
+		if mp.spends[key] == txID {
+			delete(mp.spends, key)
+		}
+	}
+	delete(mp.entries, txID)
+}
+
+func (mp *Mempool) removeWithDescendants(txID string) {
+	if _, ok := mp.entries[txID]; !ok {
+		return
+	}
+	for _, id := range mp.descendants(txID) {
+		mp.remove(id)
+	}
+}
+
+// Remove 交易被打包進區塊後移出 mempool
+func (mp *Mempool) Remove(txID string) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.remove(txID)
+}
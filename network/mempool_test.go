@@ -0,0 +1,37 @@
+package network
+
+import (
+	"blockchain/blockchain"
+	"blockchain/blockchain/chaintest"
+	"strings"
+	"testing"
+)
+
+// TestMempoolRejectsNonStandard 有效但不符合 standardness 的交易不能進入 mempool, 放寬規則後可以
+func TestMempoolRejectsNonStandard(t *testing.T) {
+	chain, w := chaintest.New(t)
+	dust := *chaintest.SpendGenesis(t, chain, w, 1, 99)
+
+	if !chain.VerifyTransactions([]*blockchain.Transaction{&dust}) {
+		t.Fatal("dust transaction does not verify under consensus rules")
+	}
+
+	mp := NewMempool()
+	err := mp.Add(dust, chain)
+	if err == nil || !strings.Contains(err.Error(), "non-standard") {
+		t.Fatalf("mempool accepted a dust output: %v", err)
+	}
+	if mp.Len() != 0 {
+		t.Fatal("rejected transaction left in the mempool")
+	}
+
+	if err := mp.Add(*chaintest.SpendGenesis(t, chain, w, 50, 50), chain); err != nil {
+		t.Fatalf("standard transaction rejected: %v", err)
+	}
+
+	relaxed := NewMempool()
+	relaxed.Policy.DustLimit = 0
+	if err := relaxed.Add(dust, chain); err != nil {
+		t.Fatalf("relaxed policy rejected the dust transaction: %v", err)
+	}
+}
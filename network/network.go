@@ -2,6 +2,7 @@ package network
 
 import (
 	"blockchain/blockchain"
+	"blockchain/params"
 	"bytes"
 	"encoding/gob"
 	"encoding/hex"
@@ -12,6 +13,7 @@ import (
 	"net"
 	"os"
 	"runtime"
+	"sync"
 	"syscall"
 
 	"gopkg.in/vrecan/death.v3"
@@ -26,12 +28,20 @@ const (
 var (
 	nodeAddress  string
 	minerAddress string
-	// KnownNodes ...
-	KnownNodes      = []string{"localhost:3000"}
+	// KnownNodes 第一個為種子節點, 選擇網路後由 UseSeedNode 設定
+	KnownNodes      = []string{params.MainNet.SeedNode}
 	blocksInTransit = [][]byte{}
-	memoryPool      = make(map[string]blockchain.Transaction)
+	memoryPool      = NewMempool()
+
+	// orphanBlocks 上一個區塊尚未收到的區塊, 以上一個區塊的 hash (hex) 為索引; 同步時區塊由新到舊送達
+	orphanBlocks = make(map[string][]*blockchain.Block)
+	orphanCount  int
+	blocksMu     sync.Mutex
 )
 
+// maxOrphanBlocks 最多保留的孤兒區塊數, 超過時丟棄新收到的孤兒區塊
+const maxOrphanBlocks = 1000
+
 // Addr ...
 type Addr struct {
 	AddrList []string
@@ -68,9 +78,10 @@ type Tx struct {
 	Transaction []byte
 }
 
-// Version ...
+// Version Magic 為發送者所在網路的 params.Magic
 type Version struct {
 	Version    int
+	Magic      uint32
 	BestHeight int
 	AddrFrom   string
 }
@@ -143,7 +154,7 @@ func SendTx(addr string, tnx *blockchain.Transaction) {
 // SendVersion ...
 func SendVersion(addr string, chain *blockchain.BlockChain) {
 	bestHeight := chain.GetBestHeight()
-	payload := GobEncode(Version{version, bestHeight, nodeAddress})
+	payload := GobEncode(Version{version, params.Active.Magic, bestHeight, nodeAddress})
 	request := append(CmdToBytes("version"), payload...)
 
 	SendData(addr, request)
@@ -170,15 +181,7 @@ func SendData(addr string, data []byte) {
 	conn, err := net.Dial(protocol, addr)
 	if err != nil {
 		fmt.Printf("%s is not available\n", addr)
-		var updatedNodes []string
-
-		for _, node := range KnownNodes {
-			if node != addr {
-				updatedNodes = append(updatedNodes, node)
-			}
-		}
-
-		KnownNodes = updatedNodes
+		removeNode(addr)
 
 		return
 	}
@@ -190,6 +193,23 @@ func SendData(addr string, data []byte) {
 	}
 }
 
+func removeNode(addr string) {
+	var updatedNodes []string
+
+	for _, node := range KnownNodes {
+		if node != addr {
+			updatedNodes = append(updatedNodes, node)
+		}
+	}
+
+	KnownNodes = updatedNodes
+}
+
+// UseSeedNode 以目前網路的種子節點重設已知節點
+func UseSeedNode() {
+	KnownNodes = []string{params.Active.SeedNode}
+}
+
 // CloseDB ...
 func CloseDB(chain *blockchain.BlockChain) {
 	d := death.NewDeath(syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
@@ -277,12 +297,16 @@ func HandleBlock(request []byte, chain *blockchain.BlockChain) {
 	}
 
 	blockData := payload.Block
-	block := blockchain.Deserialize(blockData)
+	block, err := blockchain.Deserialize(blockData)
 
 	fmt.Println("Received a new block!")
-	chain.AddBlock(block)
-
-	fmt.Printf("add block %x\n", block.Hash)
+	if err != nil {
+		fmt.Printf("reject block: %s\n", err)
+	} else if _, err := chain.GetBlock(block.Hash); err != nil {
+		blocksMu.Lock()
+		connectBlock(block, chain)
+		blocksMu.Unlock()
+	}
 
 	if len(blocksInTransit) > 0 {
 		blockHash := blocksInTransit[0]
@@ -290,11 +314,42 @@ func HandleBlock(request []byte, chain *blockchain.BlockChain) {
 
 		blocksInTransit = blocksInTransit[1:]
 	} else {
-		UTXOSet := blockchain.UTXOSet{chain}
+		UTXOSet := blockchain.UTXOSet{BlockChain: chain}
 		UTXOSet.ReIndex()
 	}
 }
 
+// connectBlock 每個收到的區塊都先以 VerifyBlock 驗證才加入鏈中, 之後連接等待此區塊的孤兒區塊
+func connectBlock(block *blockchain.Block, chain *blockchain.BlockChain) {
+	err := chain.VerifyBlock(block)
+	if err == blockchain.ErrUnknownParent && len(block.PrevHash) > 0 {
+		if orphanCount >= maxOrphanBlocks {
+			fmt.Printf("drop orphan block %x\n", block.Hash)
+			return
+		}
+		key := hex.EncodeToString(block.PrevHash)
+		orphanBlocks[key] = append(orphanBlocks[key], block)
+		orphanCount++
+		fmt.Printf("hold block %x until its parent arrives\n", block.Hash)
+		return
+	}
+	if err != nil {
+		fmt.Printf("reject block %x: %s\n", block.Hash, err)
+		return
+	}
+
+	chain.AddBlock(block)
+	fmt.Printf("add block %x\n", block.Hash)
+
+	key := hex.EncodeToString(block.Hash)
+	children := orphanBlocks[key]
+	delete(orphanBlocks, key)
+	orphanCount -= len(children)
+	for _, child := range children {
+		connectBlock(child, chain)
+	}
+}
+
 // HandleGetBlocks ...
 func HandleGetBlocks(request []byte, chain *blockchain.BlockChain) {
 	var buff bytes.Buffer
@@ -334,7 +389,10 @@ func HandleGetData(request []byte, chain *blockchain.BlockChain) {
 
 	if payload.Type == "tx" {
 		txID := hex.EncodeToString(payload.ID)
-		tx := memoryPool[txID]
+		tx, ok := memoryPool.Get(txID)
+		if !ok {
+			return
+		}
 
 		SendTx(payload.AddrFrom, &tx)
 	}
@@ -352,6 +410,13 @@ func HandleVersion(request []byte, chain *blockchain.BlockChain) {
 		log.Panic(err)
 	}
 
+	// 其他網路的節點不同步區塊, 也不加入已知節點
+	if payload.Magic != params.Active.Magic {
+		fmt.Printf("reject version from %s: network magic %08x is not %s\n", payload.AddrFrom, payload.Magic, params.Active.Name)
+		removeNode(payload.AddrFrom)
+		return
+	}
+
 	bestHeight := chain.GetBestHeight()
 	otherHeight := payload.BestHeight
 
@@ -380,9 +445,13 @@ func HandleTx(request []byte, chain *blockchain.BlockChain) {
 
 	txData := payload.Transaction
 	tx := blockchain.DeserializeTransaction(txData)
-	memoryPool[hex.EncodeToString(tx.ID)] = tx
+	// 驗證成功的簽章會留在快取中, 之後打包或收到區塊時不會重複驗證
+	if err := memoryPool.Add(tx, chain); err != nil {
+		fmt.Printf("reject transaction %x: %s\n", tx.ID, err)
+		return
+	}
 
-	fmt.Printf("%s, %d\n", nodeAddress, len(memoryPool))
+	fmt.Printf("%s, %d\n", nodeAddress, memoryPool.Len())
 
 	if nodeAddress == KnownNodes[0] {
 		for _, node := range KnownNodes {
@@ -391,43 +460,57 @@ func HandleTx(request []byte, chain *blockchain.BlockChain) {
 			}
 		}
 	} else {
-		if len(memoryPool) >= 2 && len(minerAddress) > 0 {
+		if memoryPool.Len() >= 2 && len(minerAddress) > 0 {
 			MineTx(chain)
 		}
 	}
 
-	fmt.Println("%s")
 }
 
-// MineTx ...
+// MineTx coinbase 必須是區塊的第一筆交易, 否則其他節點的 ValidateBlock 會拒絕此區塊;
+// coinbase 領取區塊獎勵加上選入交易的手續費
 func MineTx(chain *blockchain.BlockChain) {
 	var txs []*blockchain.Transaction
+	selected := make(map[string]blockchain.Transaction)
+	fees := 0
 
-	for id := range memoryPool {
-		fmt.Printf("tx: %s\n", memoryPool[id].ID)
-		tx := memoryPool[id]
+	// 父交易排在子交易之前, 子交易可以花費同一個區塊中的輸出; 以最大金額的 coinbase 保留空間
+	reserved := blockchain.CoinbaseTx(minerAddress, "", blockchain.MaxMoney).Size()
+	for _, tx := range memoryPool.BlockTemplate(blockchain.MaxBlockSize - reserved) {
+		tx := tx
+		txID := hex.EncodeToString(tx.ID)
+		fmt.Printf("tx: %s\n", txID)
 
-		if chain.VerifyTransaction(&tx) {
-			txs = append(txs, &tx)
+		if !chain.VerifyTransactionsWith([]*blockchain.Transaction{&tx}, selected) {
+			memoryPool.Remove(txID)
+			continue
+		}
+		prevOuts, err := chain.PrevOutputsWith(&tx, selected)
+		if err != nil {
+			memoryPool.Remove(txID)
+			continue
 		}
+		fees += tx.Fee(prevOuts)
+		txs = append(txs, &tx)
+		selected[txID] = tx
 	}
 
 	if len(txs) == 0 {
 		fmt.Println("All transactions are invalid")
 	}
 
-	cbTX := blockchain.CoinbaseTx(minerAddress, "")
-	txs = append(txs, cbTX)
+	cbTX := blockchain.CoinbaseTx(minerAddress, "", fees)
+	txs = append([]*blockchain.Transaction{cbTX}, txs...)
 
 	newBlock := chain.MineBlock(txs)
-	UTXOSet := blockchain.UTXOSet{chain}
+	UTXOSet := blockchain.UTXOSet{BlockChain: chain}
 	UTXOSet.ReIndex()
 
 	fmt.Println("New Block mined")
 
 	for _, tx := range txs {
 		txID := hex.EncodeToString(tx.ID)
-		delete(memoryPool, txID)
+		memoryPool.Remove(txID)
 	}
 
 	for _, node := range KnownNodes {
@@ -436,7 +519,8 @@ func MineTx(chain *blockchain.BlockChain) {
 		}
 	}
 
-	if len(memoryPool) > 0 {
+	// 剩下的交易放不進這個區塊時繼續挖下一個, 一筆都沒選入時停止以免無限遞迴
+	if memoryPool.Len() > 0 && len(txs) > 1 {
 		MineTx(chain)
 	}
 }
@@ -473,7 +557,7 @@ func HandleInv(request []byte, chain *blockchain.BlockChain) {
 	if payload.Type == "tx" {
 		txID := payload.Items[0]
 
-		if memoryPool[hex.EncodeToString(txID)].ID == nil {
+		if _, ok := memoryPool.Get(hex.EncodeToString(txID)); !ok {
 			SendGetData(payload.AddrFrom, "tx", txID)
 		}
 	}
@@ -496,9 +580,11 @@ func RequestBlocks() {
 	}
 }
 
-func StartServer(nodeID, minerAddress string) {
+// StartServer policy 決定節點接受與轉送哪些未確認交易
+func StartServer(nodeID, minerAddr string, policy blockchain.Policy) {
 	nodeAddress = fmt.Sprintf("localhost:%s", nodeID)
-	minerAddress = minerAddress
+	minerAddress = minerAddr
+	memoryPool.Policy = policy
 
 	ln, err := net.Listen(protocol, nodeAddress)
 	if err != nil {
@@ -0,0 +1,78 @@
+package network
+
+import (
+	"blockchain/blockchain"
+	"blockchain/blockchain/chaintest"
+	"blockchain/params"
+	"encoding/hex"
+	"testing"
+)
+
+// TestMineTxBlockVerifies MineTx 挖出的區塊必須通過其他節點收到區塊時的 VerifyBlock, coinbase 領取選入交易的手續費
+func TestMineTxBlockVerifies(t *testing.T) {
+	chain, w := chaintest.New(t)
+
+	oldPool, oldMiner, oldNodes := memoryPool, minerAddress, KnownNodes
+	memoryPool, minerAddress, KnownNodes = NewMempool(), string(w.Address()), nil
+	t.Cleanup(func() { memoryPool, minerAddress, KnownNodes = oldPool, oldMiner, oldNodes })
+
+	// 子交易花費同一個區塊中父交易的輸出
+	parent := chaintest.SpendGenesis(t, chain, w, 60, 40)
+	child := &blockchain.Transaction{
+		Inputs:  []blockchain.TxInput{{ID: parent.ID, Out: 0, PubKey: w.Publickey, Sequence: blockchain.MaxSequence}},
+		Outputs: []blockchain.TxOutput{parent.Outputs[0]},
+	}
+	child.Outputs[0].Value -= 5
+	child.SetID()
+	child.Sign(w.PrivateKey, map[string]blockchain.Transaction{hex.EncodeToString(parent.ID): *parent})
+
+	for _, tx := range []*blockchain.Transaction{parent, child} {
+		if err := memoryPool.Add(*tx, chain); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	MineTx(chain)
+
+	block, err := chain.GetBlock(chain.LastHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.Transaction) != 3 || !block.Transaction[0].IsCoinbase() {
+		t.Fatalf("mined block has %d transactions, first is coinbase: %v", len(block.Transaction), block.Transaction[0].IsCoinbase())
+	}
+	if err := chain.VerifyBlock(&block); err != nil {
+		t.Fatalf("mined block does not verify: %v", err)
+	}
+	if got := block.Transaction[0].Outputs[0].Value; got != blockchain.Subsidy+5 {
+		t.Errorf("coinbase pays %d, want the subsidy plus the 5 fee", got)
+	}
+	if memoryPool.Len() != 0 {
+		t.Errorf("%d transactions left in the mempool", memoryPool.Len())
+	}
+}
+
+// TestHandleVersionMagic 其他網路的節點不會加入已知節點, 同一網路的節點會
+func TestHandleVersionMagic(t *testing.T) {
+	chain, _ := chaintest.New(t)
+
+	oldNodes := KnownNodes
+	KnownNodes = []string{"localhost:13001"}
+	t.Cleanup(func() { KnownNodes = oldNodes })
+
+	// 高度與本節點相同, 握手時不會送出任何訊息
+	height := chain.GetBestHeight()
+	request := func(magic uint32, addr string) []byte {
+		return append(CmdToBytes("version"), GobEncode(Version{version, magic, height, addr})...)
+	}
+
+	HandleVersion(request(params.TestNet.Magic, "localhost:13001"), chain)
+	if NodeIsKnown("localhost:13001") {
+		t.Error("testnet node is still known on mainnet")
+	}
+
+	HandleVersion(request(params.MainNet.Magic, "localhost:3002"), chain)
+	if !NodeIsKnown("localhost:3002") {
+		t.Error("mainnet node is not known after the handshake")
+	}
+}
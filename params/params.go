@@ -0,0 +1,129 @@
+package params
+
+import (
+	"fmt"
+	"os"
+)
+
+// Params 網路參數, 不同網路的地址, 私鑰與創世區塊互不相容;
+// version 與前綴都不與 Bitcoin 相同, 避免地址被其他鏈的錢包接受
+type Params struct {
+	Name                    string
+	AddressVersion          byte   // Base58Check 地址的 version
+	LegacyAddressVersion    byte   // 舊版錢包檔案中地址的 version, 載入時轉換為 AddressVersion
+	PrivateKeyVersion       byte   // 匯出私鑰的 version
+	LegacyPrivateKeyVersion byte   // 舊版匯出私鑰的 version, 仍可匯入
+	Bech32HRP               string // Bech32 地址的可讀前綴
+	HDCoinType              uint32 // BIP44 路徑 m/44'/coin'/0' 中的 coin type
+	GenesisData             string // 創世區塊 coinbase 的資料
+	Magic                   uint32 // 節點握手時交換, 不同網路的節點互相拒絕
+	SeedNode                string // 預設連線的節點, 每個網路使用不同的埠號
+}
+
+var (
+	// MainNet 長期運作的網路, 沿用原本的創世資料
+	MainNet = Params{
+		Name:                    "mainnet",
+		AddressVersion:          0x19,
+		LegacyAddressVersion:    0x00,
+		PrivateKeyVersion:       0x99,
+		LegacyPrivateKeyVersion: 0x80,
+		Bech32HRP:               "blk",
+		HDCoinType:              0x424c4b, // "BLK", 不與 Bitcoin 的 0 共用 HD 路徑
+		GenesisData:             "First Transaction from Genesis",
+		Magic:                   0x424c4b01,
+		SeedNode:                "localhost:3000",
+	}
+
+	// TestNet 公開的測試網路
+	TestNet = Params{
+		Name:                    "testnet",
+		AddressVersion:          0x41,
+		LegacyAddressVersion:    0x6f,
+		PrivateKeyVersion:       0xc1,
+		LegacyPrivateKeyVersion: 0xef,
+		Bech32HRP:               "tblk",
+		HDCoinType:              1,
+		GenesisData:             "First Transaction from Testnet Genesis",
+		Magic:                   0x424c4b02,
+		SeedNode:                "localhost:13000",
+	}
+
+	// RegTest 本機測試用的網路
+	RegTest = Params{
+		Name:                    "regtest",
+		AddressVersion:          0x3c,
+		LegacyAddressVersion:    0x7a,
+		PrivateKeyVersion:       0xbc,
+		LegacyPrivateKeyVersion: 0xf1,
+		Bech32HRP:               "rblk",
+		HDCoinType:              1,
+		GenesisData:             "First Transaction from Regtest Genesis",
+		Magic:                   0x424c4b03,
+		SeedNode:                "localhost:23000",
+	}
+
+	// Networks 所有已知的網路
+	Networks = []*Params{&MainNet, &TestNet, &RegTest}
+
+	// Active 目前使用的網路, 由 NETWORK 環境變數選擇
+	Active = &MainNet
+)
+
+// Select 依名稱切換目前使用的網路, 空字串為 mainnet
+func Select(name string) error {
+	if name == "" {
+		Active = &MainNet
+		return nil
+	}
+	for _, p := range Networks {
+		if p.Name == name {
+			Active = p
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown network %q", name)
+}
+
+// SelectFromEnv 以 NETWORK 環境變數選擇網路
+func SelectFromEnv() error {
+	return Select(os.Getenv("NETWORK"))
+}
+
+// ByAddressVersion 找出使用此 version 的網路
+func ByAddressVersion(version byte) *Params {
+	for _, p := range Networks {
+		if p.AddressVersion == version {
+			return p
+		}
+	}
+	return nil
+}
+
+// ByPrivateKeyVersion 找出使用此私鑰 version 的網路
+func ByPrivateKeyVersion(version byte) *Params {
+	for _, p := range Networks {
+		if p.PrivateKeyVersion == version {
+			return p
+		}
+	}
+	return nil
+}
+
+// ByBech32HRP 找出使用此前綴的網路
+func ByBech32HRP(hrp string) *Params {
+	for _, p := range Networks {
+		if p.Bech32HRP == hrp {
+			return p
+		}
+	}
+	return nil
+}
+
+// Path 產生節點的資料路徑, mainnet 維持原本的檔名, 其他網路在 nodeID 之後加上網路名稱
+func (p *Params) Path(format, nodeID string) string {
+	if p != &MainNet {
+		nodeID += "_" + p.Name
+	}
+	return fmt.Sprintf(format, nodeID)
+}
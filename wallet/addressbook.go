@@ -0,0 +1,161 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// 地址簿 CSV 每行為 kind,name,address, kind 為 label (錢包地址的標籤) 或 contact (外部地址)
+const (
+	bookLabel   = "label"
+	bookContact = "contact"
+)
+
+// ContactPrefix send -to @name 以聯絡人名稱代替地址
+const ContactPrefix = "@"
+
+// Label 回傳錢包地址的標籤
+func (ws *Wallets) Label(address string) string {
+	return ws.Labels[normalizeKey(address)]
+}
+
+// SetLabel 設定錢包地址 (包括觀察地址) 的標籤, 空字串移除標籤
+func (ws *Wallets) SetLabel(address, label string) error {
+	if !ws.hasAddress(address) {
+		return fmt.Errorf("%s is not in the wallet", address)
+	}
+	address = normalizeKey(address)
+
+	if label == "" {
+		delete(ws.Labels, address)
+		return nil
+	}
+	if ws.Labels == nil {
+		ws.Labels = make(map[string]string)
+	}
+	ws.Labels[address] = label
+	return nil
+}
+
+// hasAddress 地址是否為錢包地址或觀察地址
+func (ws *Wallets) hasAddress(address string) bool {
+	address = normalizeKey(address)
+	_, ok := ws.Wallets[address]
+	return ok || ws.Watch[address]
+}
+
+// validContactName 名稱會出現在 -to NAME:AMOUNT 與 CSV 中, 不可包含分隔字元
+func validContactName(name string) error {
+	if name == "" || strings.ContainsAny(name, ":,@ \t\n") {
+		return fmt.Errorf("contact name %q must be non-empty without spaces, ':', ',' or '@'", name)
+	}
+	return nil
+}
+
+// AddContact 新增或更新聯絡人
+func (ws *Wallets) AddContact(name, address string) error {
+	if err := validContactName(name); err != nil {
+		return err
+	}
+	if !ValidateAddress(address) {
+		return fmt.Errorf("address of %s is not valid", name)
+	}
+
+	if ws.Contacts == nil {
+		ws.Contacts = make(map[string]string)
+	}
+	ws.Contacts[name] = address
+	return nil
+}
+
+// RemoveContact ...
+func (ws *Wallets) RemoveContact(name string) error {
+	if _, ok := ws.Contacts[name]; !ok {
+		return fmt.Errorf("unknown contact %q", name)
+	}
+	delete(ws.Contacts, name)
+	return nil
+}
+
+// ContactNames 依字母排序的聯絡人名稱
+func (ws *Wallets) ContactNames() []string {
+	var names []string
+	for name := range ws.Contacts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveAddress 把 @name 轉換為聯絡人的地址, 其他字串原樣回傳
+func (ws *Wallets) ResolveAddress(s string) (string, error) {
+	if !strings.HasPrefix(s, ContactPrefix) {
+		return s, nil
+	}
+
+	address, ok := ws.Contacts[strings.TrimPrefix(s, ContactPrefix)]
+	if !ok {
+		return "", fmt.Errorf("unknown contact %q", s)
+	}
+	return address, nil
+}
+
+// WriteAddressBook 以 CSV 匯出標籤與聯絡人
+func (ws *Wallets) WriteAddressBook(w io.Writer) error {
+	out := csv.NewWriter(w)
+
+	var addresses []string
+	for address := range ws.Labels {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		if err := out.Write([]string{bookLabel, ws.Labels[address], address}); err != nil {
+			return err
+		}
+	}
+	for _, name := range ws.ContactNames() {
+		if err := out.Write([]string{bookContact, name, ws.Contacts[name]}); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// ReadAddressBook 匯入 WriteAddressBook 的輸出, 已存在的項目會被覆蓋,
+// 不在此錢包中的地址的標籤會被略過; 回傳匯入的項目數
+func (ws *Wallets) ReadAddressBook(r io.Reader) (int, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for i, record := range records {
+		if len(record) != 3 {
+			return imported, fmt.Errorf("line %d: address book line is not kind,name,address", i+1)
+		}
+
+		switch record[0] {
+		case bookLabel:
+			if !ws.hasAddress(record[2]) {
+				continue
+			}
+			err = ws.SetLabel(record[2], record[1])
+		case bookContact:
+			err = ws.AddContact(record[1], record[2])
+		default:
+			err = fmt.Errorf("unknown address book entry %q", record[0])
+		}
+		if err != nil {
+			return imported, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		imported++
+	}
+	return imported, nil
+}
@@ -0,0 +1,237 @@
+package wallet
+
+import (
+	"blockchain/params"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// BIP173 與 BIP350 的檢查碼常數: P256 地址使用 Bech32, 其他類型使用 Bech32m
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// bech32MaxLength 含前綴與檢查碼的最大長度
+const bech32MaxLength = 90
+
+func bech32Polymod(values []byte) uint32 {
+	gen := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range []byte(hrp) {
+		expanded = append(expanded, c>>5)
+	}
+	expanded = append(expanded, 0)
+	for _, c := range []byte(hrp) {
+		expanded = append(expanded, c&31)
+	}
+	return expanded
+}
+
+func bech32Checksum(hrp string, data []byte, constant uint32) []byte {
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ constant
+
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(polymod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+// bech32Encode data 為 5 bits 一組的值
+func bech32Encode(hrp string, data []byte, constant uint32) string {
+	combined := append(append([]byte{}, data...), bech32Checksum(hrp, data, constant)...)
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range combined {
+		sb.WriteByte(bech32Charset[v])
+	}
+	return sb.String()
+}
+
+// bech32Decode 回傳前綴, 去除檢查碼的資料與使用的檢查碼常數; 拒絕大小寫混用
+func bech32Decode(s string) (string, []byte, uint32, error) {
+	if len(s) > bech32MaxLength {
+		return "", nil, 0, errors.New("bech32 string is too long")
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, errors.New("bech32 string mixes upper and lower case")
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, 0, errors.New("bech32 separator is misplaced")
+	}
+
+	hrp := s[:sep]
+	for _, c := range []byte(hrp) {
+		if c < 33 || c > 126 {
+			return "", nil, 0, errors.New("bech32 prefix has an invalid character")
+		}
+	}
+
+	data := make([]byte, 0, len(s)-sep-1)
+	for _, c := range []byte(s[sep+1:]) {
+		v := strings.IndexByte(bech32Charset, c)
+		if v < 0 {
+			return "", nil, 0, errors.New("bech32 string has an invalid character")
+		}
+		data = append(data, byte(v))
+	}
+
+	constant := bech32Polymod(append(bech32HRPExpand(hrp), data...))
+	if constant != bech32Const && constant != bech32mConst {
+		return "", nil, 0, errors.New("bech32 checksum mismatch")
+	}
+	return hrp, data[:len(data)-6], constant, nil
+}
+
+// convertBits 在 8 bits 與 5 bits 一組之間轉換
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var (
+		acc  uint32
+		bits uint
+		out  []byte
+	)
+	maxv := uint32(1)<<to - 1
+	for _, v := range data {
+		if uint32(v)>>from != 0 {
+			return nil, errors.New("value out of range")
+		}
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return out, nil
+}
+
+// EncodeBech32Address Bech32 地址: 第一個 5 bits 為金鑰類型, 其後為 public key hash;
+// 結構與 segwit 地址相同, 因此前綴不能是 Bitcoin 使用的 bc / tb / bcrt
+func EncodeBech32Address(t KeyType, pubKeyHash []byte) string {
+	data, err := convertBits(pubKeyHash, 8, 5, true)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	constant := uint32(bech32mConst)
+	if t == KeyP256 {
+		constant = bech32Const
+	}
+	return bech32Encode(params.Active.Bech32HRP, append([]byte{byte(t)}, data...), constant)
+}
+
+// decodeBech32Address 解析 Bech32 地址, 檢查前綴, 類型與檢查碼常數是否相符
+func decodeBech32Address(address string) (KeyType, []byte, error) {
+	hrp, data, constant, err := bech32Decode(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if hrp != params.Active.Bech32HRP {
+		if p := params.ByBech32HRP(hrp); p != nil {
+			return 0, nil, fmt.Errorf("address belongs to %s, not %s", p.Name, params.Active.Name)
+		}
+		return 0, nil, errors.New("address has unknown prefix")
+	}
+	if len(data) < 1 {
+		return 0, nil, errors.New("address is too short")
+	}
+
+	t := KeyType(data[0])
+	if !t.Valid() {
+		return 0, nil, errors.New("address has unknown key type")
+	}
+	if (t == KeyP256) != (constant == bech32Const) {
+		return 0, nil, errors.New("address uses the wrong checksum variant")
+	}
+
+	pubKeyHash, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(pubKeyHash) != ripemd160.Size {
+		return 0, nil, errors.New("address has wrong length")
+	}
+	return t, pubKeyHash, nil
+}
+
+// isBech32Address 以任一網路的可讀前綴判斷地址格式, 其他網路的地址由 decodeBech32Address 拒絕
+func isBech32Address(address string) bool {
+	for _, p := range params.Networks {
+		if strings.HasPrefix(strings.ToLower(address), p.Bech32HRP+"1") {
+			return true
+		}
+	}
+	return false
+}
+
+// AddressFormat 顯示地址的格式, 兩種格式都可以用於收款
+type AddressFormat byte
+
+const (
+	AddressBase58 AddressFormat = iota
+	AddressBech32
+)
+
+func (f AddressFormat) String() string {
+	if f == AddressBech32 {
+		return "bech32"
+	}
+	return "base58"
+}
+
+// ParseAddressFormat 解析 -format 參數
+func ParseAddressFormat(name string) (AddressFormat, error) {
+	for _, f := range []AddressFormat{AddressBase58, AddressBech32} {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown address format %q", name)
+}
+
+// FormatAddress 以指定格式重新編碼地址
+func FormatAddress(address string, f AddressFormat) (string, error) {
+	t, pubKeyHash, err := DecodeAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if f == AddressBech32 {
+		return EncodeBech32Address(t, pubKeyHash), nil
+	}
+	return string(encodeBase58Address(t, pubKeyHash)), nil
+}
@@ -0,0 +1,194 @@
+package wallet
+
+import (
+	"blockchain/params"
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+// bech32Valid BIP173 與 BIP350 的有效字串, 以及使用的檢查碼常數
+var bech32Valid = []struct {
+	s        string
+	constant uint32
+}{
+	{"A12UEL5L", bech32Const},
+	{"a12uel5l", bech32Const},
+	{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", bech32Const},
+	{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", bech32Const},
+	{"11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqc8247j", bech32Const},
+	{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", bech32Const},
+	{"?1ezyfcl", bech32Const},
+	{"A1LQFN3A", bech32mConst},
+	{"a1lqfn3a", bech32mConst},
+	{"an83characterlonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11sg7hg6", bech32mConst},
+	{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", bech32mConst},
+	{"11llllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllllludsr8", bech32mConst},
+	{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", bech32mConst},
+	{"?1v759aa", bech32mConst},
+}
+
+// bech32Invalid BIP173 與 BIP350 的無效字串, 兩種檢查碼都不接受
+var bech32Invalid = []struct {
+	s, reason string
+}{
+	{"\x201nwldj5", "HRP character out of range"},
+	{"\x7f1axkwrx", "HRP character out of range"},
+	{"\x801eym55h", "HRP character out of range"},
+	{"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", "overall max length exceeded"},
+	{"pzry9x0s0muk", "no separator character"},
+	{"1pzry9x0s0muk", "empty HRP"},
+	{"x1b4n0q5v", "invalid data character"},
+	{"li1dgmt3", "too short checksum"},
+	{"de1lg7wt\xff", "invalid character in checksum"},
+	{"A1G7SGD8", "checksum calculated with uppercase form of HRP"},
+	{"10a06t8", "empty HRP"},
+	{"1qzzfhee", "empty HRP"},
+	{"\x201xj0phk", "HRP character out of range"},
+	{"\x7f1g6xzxy", "HRP character out of range"},
+	{"\x801vctc34", "HRP character out of range"},
+	{"an84characterslonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11d6pts4", "overall max length exceeded"},
+	{"qyrz8wqd2c9m", "no separator character"},
+	{"1qyrz8wqd2c9m", "empty HRP"},
+	{"y1b0jsk6g", "invalid data character"},
+	{"lt1igcx5c0", "invalid data character"},
+	{"in1muywd", "too short checksum"},
+	{"mm1crxm3i", "invalid character in checksum"},
+	{"au1s5cgom", "invalid character in checksum"},
+	{"M1VUXWEZ", "checksum calculated with uppercase form of HRP"},
+	{"16plkw9", "empty HRP"},
+	{"1p2gdwpf", "empty HRP"},
+}
+
+func TestBech32Vectors(t *testing.T) {
+	for _, v := range bech32Valid {
+		hrp, data, constant, err := bech32Decode(v.s)
+		if err != nil {
+			t.Errorf("%s: %v", v.s, err)
+			continue
+		}
+		if constant != v.constant {
+			t.Errorf("%s: constant %x, want %x", v.s, constant, v.constant)
+		}
+		if got := bech32Encode(hrp, data, constant); got != strings.ToLower(v.s) {
+			t.Errorf("%s: re-encoded as %s", v.s, got)
+		}
+
+		// 修改任一字元後檢查碼必須失效
+		flipped := []byte(strings.ToLower(v.s))
+		i := strings.LastIndexByte(string(flipped), '1') + 1
+		flipped[i] = bech32Charset[(strings.IndexByte(bech32Charset, flipped[i])+1)%32]
+		if _, _, _, err := bech32Decode(string(flipped)); err == nil {
+			t.Errorf("%s: one changed character is accepted", v.s)
+		}
+	}
+
+	for _, v := range bech32Invalid {
+		if _, _, _, err := bech32Decode(v.s); err == nil {
+			t.Errorf("%q (%s) is accepted", v.s, v.reason)
+		}
+	}
+}
+
+func TestConvertBits(t *testing.T) {
+	// abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw 的資料部分為 0 到 31
+	groups := make([]byte, 32)
+	for i := range groups {
+		groups[i] = byte(i)
+	}
+	want := mustHex(t, "00443214c74254b635cf84653a56d7c675be77df")
+
+	got, err := convertBits(groups, 5, 8, false)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("5 to 8 bits = %x, %v, want %x", got, err, want)
+	}
+	if back, err := convertBits(want, 8, 5, true); err != nil || !bytes.Equal(back, groups) {
+		t.Errorf("8 to 5 bits = %v, %v", back, err)
+	}
+
+	if got, err := convertBits([]byte{0xff}, 8, 5, true); err != nil || !bytes.Equal(got, []byte{31, 28}) {
+		t.Errorf("0xff padded = %v, %v, want [31 28]", got, err)
+	}
+
+	for i := 0; i < 20; i++ {
+		data := make([]byte, i)
+		rand.Read(data)
+		groups, err := convertBits(data, 8, 5, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := convertBits(groups, 5, 8, false)
+		if err != nil || !bytes.Equal(back, data) {
+			t.Errorf("%x does not survive a round trip: %x, %v", data, back, err)
+		}
+	}
+
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{"non-zero padding", []byte{31, 31}},
+		{"padding of 5 bits or more", []byte{0, 0, 0}},
+		{"value out of range", []byte{32}},
+	} {
+		if _, err := convertBits(c.data, 5, 8, false); err == nil {
+			t.Errorf("%s is accepted", c.name)
+		}
+	}
+}
+
+// TestBech32AddressRoundTrip 每個網路與金鑰類型的地址都能解析回相同的類型與 public key hash
+func TestBech32AddressRoundTrip(t *testing.T) {
+	active := params.Active
+	t.Cleanup(func() { params.Active = active })
+
+	for _, p := range params.Networks {
+		params.Active = p
+		for _, keyType := range []KeyType{KeyP256, KeySecp256k1, KeySchnorr} {
+			w := MakeWallet(keyType)
+			pubKeyHash := PublicKeyHash(w.Publickey)
+
+			address := EncodeBech32Address(keyType, pubKeyHash)
+			if !strings.HasPrefix(address, p.Bech32HRP+"1") || !isBech32Address(address) {
+				t.Errorf("%s %s: address %s", p.Name, keyType, address)
+			}
+
+			for _, s := range []string{address, strings.ToUpper(address)} {
+				gotType, gotHash, err := DecodeAddress(s)
+				if err != nil {
+					t.Fatalf("%s %s: %v", p.Name, keyType, err)
+				}
+				if gotType != keyType || !bytes.Equal(gotHash, pubKeyHash) {
+					t.Errorf("%s %s: decoded %s %x", p.Name, keyType, gotType, gotHash)
+				}
+			}
+
+			base58, err := NormalizeAddress(address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if back, err := FormatAddress(base58, AddressBech32); err != nil || back != address {
+				t.Errorf("%s %s: %s formats back to %s, %v", p.Name, keyType, base58, back, err)
+			}
+
+			// P256 地址使用 Bech32, 其他類型使用 Bech32m, 換成另一種檢查碼即無效
+			data, _ := convertBits(pubKeyHash, 8, 5, true)
+			constant := uint32(bech32Const)
+			if keyType == KeyP256 {
+				constant = bech32mConst
+			}
+			if _, _, err := decodeBech32Address(bech32Encode(p.Bech32HRP, append([]byte{byte(keyType)}, data...), constant)); err == nil {
+				t.Errorf("%s %s: address with the other checksum variant is accepted", p.Name, keyType)
+			}
+		}
+	}
+
+	// 其他網路的地址被拒絕
+	params.Active = &params.MainNet
+	address := EncodeBech32Address(KeySecp256k1, make([]byte, 20))
+	params.Active = &params.TestNet
+	if _, _, err := DecodeAddress(address); err == nil {
+		t.Error("mainnet address is accepted on testnet")
+	}
+}
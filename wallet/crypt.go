@@ -0,0 +1,211 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// scrypt 參數, 每次加密時寫入檔案, 之後可以調整而不影響舊檔案
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLength   = 16
+)
+
+// encryptedMagic 加密錢包檔案的開頭, 沒有此開頭的檔案是舊的明文格式
+var encryptedMagic = []byte("WALLETENC1")
+
+var (
+	// ErrWalletLocked 加密的錢包尚未解鎖, 無法取得私鑰
+	ErrWalletLocked = errors.New("wallet is locked, run unlockWallet first")
+	// ErrWrongPassphrase 密碼錯誤或檔案已損毀
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// publicKey 鎖定時仍可讀取的公開資訊, 讓地址與餘額查詢不需要密碼
+type publicKey struct {
+	Type      KeyType
+	Publickey []byte
+	WatchOnly bool
+}
+
+// encryptedWallets 加密錢包檔案的內容, Ciphertext 為 AES-GCM 加密的明文錢包檔案
+type encryptedWallets struct {
+	Salt       []byte
+	N, R, P    int
+	Nonce      []byte
+	Ciphertext []byte
+	Public     map[string]publicKey
+	Watch      map[string]bool
+	Labels     map[string]string
+	Contacts   map[string]string
+}
+
+func (e *encryptedWallets) deriveKey(passphrase string) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), e.Salt, e.N, e.R, e.P, scryptKeyLen)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decrypt 以金鑰解開私鑰, 金鑰錯誤時 AES-GCM 驗證失敗
+func (e *encryptedWallets) decrypt(key []byte) (*Wallets, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, e.Nonce, e.Ciphertext, encryptedMagic)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var ws Wallets
+	if err := gob.NewDecoder(bytes.NewReader(plain)).Decode(&ws); err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
+
+func decodeEncrypted(content []byte) (*encryptedWallets, error) {
+	var e encryptedWallets
+	err := gob.NewDecoder(bytes.NewReader(content[len(encryptedMagic):])).Decode(&e)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// lockedWallets 只含公鑰的錢包, 私鑰為零值
+func (e *encryptedWallets) lockedWallets() map[string]*Wallet {
+	wallets := make(map[string]*Wallet)
+	for address, pub := range e.Public {
+		wallets[address] = &Wallet{Publickey: pub.Publickey, Type: pub.Type}
+	}
+	return wallets
+}
+
+// IsEncrypted ...
+func (ws *Wallets) IsEncrypted() bool {
+	return ws.encrypted != nil
+}
+
+// IsLocked 加密且尚未解鎖時無法簽署或新增金鑰
+func (ws *Wallets) IsLocked() bool {
+	return ws.encrypted != nil && ws.key == nil
+}
+
+// Encrypt 以新的密碼加密錢包, 已加密的錢包必須先解鎖; 需呼叫 SaveFile 寫入
+func (ws *Wallets) Encrypt(passphrase string) error {
+	if ws.IsLocked() {
+		return ErrWalletLocked
+	}
+	if passphrase == "" {
+		return errors.New("passphrase must not be empty")
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+
+	e := &encryptedWallets{Salt: salt, N: scryptN, R: scryptR, P: scryptP}
+	key, err := e.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+
+	ws.encrypted = e
+	ws.key = key
+	return nil
+}
+
+// Unlock 以密碼解開私鑰
+func (ws *Wallets) Unlock(passphrase string) error {
+	if ws.encrypted == nil {
+		return errors.New("wallet is not encrypted")
+	}
+
+	key, err := ws.encrypted.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	return ws.unlockWithKey(key)
+}
+
+func (ws *Wallets) unlockWithKey(key []byte) error {
+	plain, err := ws.encrypted.decrypt(key)
+	if err != nil {
+		return err
+	}
+
+	ws.Wallets = plain.Wallets
+	ws.HD = plain.HD
+	ws.Watch = plain.Watch
+	ws.Labels = plain.Labels
+	ws.Contacts = plain.Contacts
+	ws.key = key
+	ws.upgradeAddresses()
+	return nil
+}
+
+// ChangePassphrase 以舊密碼解鎖後改用新密碼加密; 需呼叫 SaveFile 寫入
+func (ws *Wallets) ChangePassphrase(oldPassphrase, newPassphrase string) error {
+	if err := ws.Unlock(oldPassphrase); err != nil {
+		return err
+	}
+	return ws.Encrypt(newPassphrase)
+}
+
+// encode 加密錢包內容, 公鑰另外以明文保存
+func (ws *Wallets) encode() ([]byte, error) {
+	var plain bytes.Buffer
+	if err := gob.NewEncoder(&plain).Encode(ws); err != nil {
+		return nil, err
+	}
+	if ws.encrypted == nil {
+		return plain.Bytes(), nil
+	}
+	if ws.key == nil {
+		return nil, ErrWalletLocked
+	}
+
+	gcm, err := newGCM(ws.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	e := ws.encrypted
+	e.Nonce = nonce
+	e.Ciphertext = gcm.Seal(nil, nonce, plain.Bytes(), encryptedMagic)
+	e.Public = make(map[string]publicKey)
+	for address, w := range ws.Wallets {
+		e.Public[address] = publicKey{w.Type, w.Publickey, w.IsWatchOnly()}
+	}
+	e.Watch = ws.Watch
+	e.Labels = ws.Labels
+	e.Contacts = ws.Contacts
+
+	content := bytes.NewBuffer(append([]byte{}, encryptedMagic...))
+	if err := gob.NewEncoder(content).Encode(e); err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
@@ -0,0 +1,223 @@
+package wallet
+
+import (
+	"blockchain/params"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+)
+
+const (
+	// HardenedOffset 大於等於此值的索引為強化導出, 只能由私鑰導出
+	HardenedOffset = uint32(0x80000000)
+
+	// ReceiveChain 與 ChangeChain 分開收款與找零地址: m/44'/coin'/0'/chain/index
+	ReceiveChain = uint32(0)
+	ChangeChain  = uint32(1)
+
+	// DefaultGapLimit 還原時連續這麼多個未使用的地址後停止搜尋
+	DefaultGapLimit = 20
+)
+
+// accountPath 所有 HD 地址共用的前綴 m/44'/coin'/0', coin type 依網路而定
+func accountPath() []uint32 {
+	return []uint32{44 + HardenedOffset, params.Active.HDCoinType + HardenedOffset, HardenedOffset}
+}
+
+var errInvalidChild = errors.New("derived key is invalid, use the next index")
+
+// masterSeedKey 主金鑰的 HMAC 金鑰, 依 SLIP-0010 對不同曲線使用不同的字串
+func masterSeedKey(t KeyType) []byte {
+	if t == KeyP256 {
+		return []byte("Nist256p1 seed")
+	}
+	return []byte("Bitcoin seed")
+}
+
+// extendedKey BIP32 延伸私鑰
+type extendedKey struct {
+	key       *big.Int
+	chainCode []byte
+}
+
+func newMasterKey(t KeyType, seed []byte) (*extendedKey, error) {
+	mac := hmac.New(sha512.New, masterSeedKey(t))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(sum[:32])
+	if key.Sign() == 0 || key.Cmp(t.Curve().Params().N) >= 0 {
+		return nil, errors.New("seed produces an invalid master key")
+	}
+
+	return &extendedKey{key, sum[32:]}, nil
+}
+
+// child 依 BIP32 導出子私鑰; 非強化導出以 SEC1 壓縮公鑰作為 HMAC 的輸入
+func (k *extendedKey) child(t KeyType, index uint32) (*extendedKey, error) {
+	curve := t.Curve()
+	n := curve.Params().N
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	if index >= HardenedOffset {
+		mac.Write([]byte{0})
+		mac.Write(bytes32(k.key))
+	} else {
+		var pub ecdsa.PublicKey
+		pub.Curve = curve
+		pub.X, pub.Y = curve.ScalarBaseMult(bytes32(k.key))
+		mac.Write(compressPublicKey(&pub))
+	}
+	var i [4]byte
+	binary.BigEndian.PutUint32(i[:], index)
+	mac.Write(i[:])
+	sum := mac.Sum(nil)
+
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(n) >= 0 {
+		return nil, errInvalidChild
+	}
+	key := tweak.Add(tweak, k.key)
+	key.Mod(key, n)
+	if key.Sign() == 0 {
+		return nil, errInvalidChild
+	}
+
+	return &extendedKey{key, sum[32:]}, nil
+}
+
+// deriveKey 由種子依路徑導出私鑰
+func deriveKey(t KeyType, seed []byte, path []uint32) (*big.Int, error) {
+	k, err := newMasterKey(t, seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range path {
+		if k, err = k.child(t, index); err != nil {
+			return nil, err
+		}
+	}
+	return k.key, nil
+}
+
+// HDPath 回傳地址的導出路徑字串
+func HDPath(chain, index uint32) string {
+	return fmt.Sprintf("m/44'/%d'/0'/%d/%d", params.Active.HDCoinType, chain, index)
+}
+
+// HDChain 階層式確定性錢包的種子與導出狀態, 備份助記詞即可還原所有地址
+type HDChain struct {
+	Seed  []byte
+	Type  KeyType
+	Next  [2]uint32         // 收款與找零鏈下一個要導出的索引
+	Paths map[string]string // 地址 -> 導出路徑
+}
+
+func (hd *HDChain) derive(chain, index uint32) (*Wallet, error) {
+	path := append(accountPath(), chain, index)
+	key, err := deriveKey(hd.Type, hd.Seed, path)
+	if err != nil {
+		return nil, err
+	}
+	return walletFromKey(hd.Type, key), nil
+}
+
+// InitHD 以 BIP39 種子建立 HD 錢包, 之後同類型的新地址都由種子導出
+func (ws *Wallets) InitHD(seed []byte, t KeyType) error {
+	if ws.HD != nil {
+		return errors.New("wallet already has an HD seed")
+	}
+	if !t.Valid() {
+		return fmt.Errorf("unknown key type %s", t)
+	}
+	if _, err := newMasterKey(t, seed); err != nil {
+		return err
+	}
+
+	ws.HD = &HDChain{Seed: seed, Type: t, Paths: make(map[string]string)}
+	return nil
+}
+
+// addHDWallet 導出鏈上下一個地址, 跳過無效的索引
+func (ws *Wallets) addHDWallet(chain uint32) string {
+	hd := ws.HD
+	for {
+		index := hd.Next[chain]
+		hd.Next[chain]++
+
+		w, err := hd.derive(chain, index)
+		if err == errInvalidChild {
+			continue
+		}
+		if err != nil {
+			log.Panic(err)
+		}
+
+		address := string(w.Address())
+		ws.Wallets[address] = w
+		hd.Paths[address] = HDPath(chain, index)
+		return address
+	}
+}
+
+// AddChangeWallet 新增找零地址, HD 錢包由找零鏈導出
+func (ws *Wallets) AddChangeWallet(t KeyType) string {
+	if ws.HD != nil && ws.HD.Type == t {
+		return ws.addHDWallet(ChangeChain)
+	}
+	return ws.AddWallet(t)
+}
+
+// Rescan 依序導出收款與找零鏈上的地址, 連續 gapLimit 個未使用時停止,
+// 加回最後一個已使用地址之前的所有地址; used 判斷公鑰雜湊是否出現在鏈上
+func (ws *Wallets) Rescan(used func(pubKeyHash []byte) bool, gapLimit int) (int, error) {
+	if ws.HD == nil {
+		return 0, errors.New("wallet has no HD seed")
+	}
+	if gapLimit <= 0 {
+		gapLimit = DefaultGapLimit
+	}
+
+	found := 0
+	for _, chain := range []uint32{ReceiveChain, ChangeChain} {
+		var wallets []*Wallet
+		var paths []string
+		last := -1
+
+		for index, unused := uint32(0), 0; unused < gapLimit; index++ {
+			w, err := ws.HD.derive(chain, index)
+			if err == errInvalidChild {
+				continue
+			}
+			if err != nil {
+				return found, err
+			}
+
+			wallets = append(wallets, w)
+			paths = append(paths, HDPath(chain, index))
+			if used(PublicKeyHash(w.Publickey)) {
+				last = len(wallets) - 1
+				unused = 0
+				found++
+				if index+1 > ws.HD.Next[chain] {
+					ws.HD.Next[chain] = index + 1
+				}
+			} else {
+				unused++
+			}
+		}
+
+		for i := 0; i <= last; i++ {
+			address := string(wallets[i].Address())
+			ws.Wallets[address] = wallets[i]
+			ws.HD.Paths[address] = paths[i]
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,196 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"testing"
+)
+
+// bip39Vectors BIP39 英文單字表的官方測試向量 (trezor/python-mnemonic vectors.json), 種子的密碼皆為 "TREZOR"
+var bip39Vectors = []struct {
+	entropy, mnemonic, seed string
+}{
+	{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow", "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607"},
+	{"80808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage above", "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8"},
+	{"ffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong", "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069"},
+	{"000000000000000000000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon agent", "035895f2f481b1b0f01fcf8c289c794660b289981a78f8106447707fdd9666ca06da5a9a565181599b79f53b844d8a71dd9f439c52a3d7b3e8a79c906ac845fa"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal will", "f2b94508732bcbacbcc020faefecfc89feafa6649a5491b8c952cede496c214a0c7b3c392d168748f2d4a612bada0753b52a1c7ac53c1e93abd5c6320b9e95dd"},
+	{"808080808080808080808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter always", "107d7c02a5aa6f38c58083ff74f04c607c2d2c0ecc55501dadd72d025b751bc27fe913ffb796f841c49b1d33b610cf0e91d3aa239027f5e99fe4ce9e5088cd65"},
+	{"ffffffffffffffffffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo when", "0cd6e5d827bb62eb8fc1e262254223817fd068a74b5b449cc2f667c3f1f985a76379b43348d952e2265b4cd129090758b3e3c2c49103b5051aac2eaeb890a528"},
+	{"0000000000000000000000000000000000000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art", "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title", "bc09fca1804f7e69da93c2f2028eb238c227f2e9dda30cd63699232578480a4021b146ad717fbb7e451ce9eb835f43620bf5c514db0f8add49f5d121449d3e87"},
+	{"8080808080808080808080808080808080808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic bless", "c0c519bd0e91a2ed54357d9d1ebef6f5af218a153624cf4f2da911a0ed8f7a09e2ef61af0aca007096df430022f7a2b6fb91661a9589097069720d015e4e982f"},
+	{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote", "dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad"},
+	{"9e885d952ad362caeb4efe34a8e91bd2", "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic", "274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028"},
+	{"6610b25967cdcca9d59875f5cb50b0ea75433311869e930b", "gravity machine north sort system female filter attitude volume fold club stay feature office ecology stable narrow fog", "628c3827a8823298ee685db84f55caa34b5cc195a778e52d45f59bcf75aba68e4d7590e101dc414bc1bbd5737666fbbef35d1f1903953b66624f910feef245ac"},
+	{"68a79eaca2324873eacc50cb9c6eca8cc68ea5d936f98787c60c7ebc74e6ce7c", "hamster diagram private dutch cause delay private meat slide toddler razor book happy fancy gospel tennis maple dilemma loan word shrug inflict delay length", "64c87cde7e12ecf6704ab95bb1408bef047c22db4cc7491c4271d170a1b213d20b385bc1588d9c7b38f1b39d415665b8a9030c9ec653d75e65f847d8fc1fc440"},
+	{"c0ba5a8e914111210f2bd131f3d5e08d", "scheme spot photo card baby mountain device kick cradle pact join borrow", "ea725895aaae8d4c1cf682c1bfd2d358d52ed9f0f0591131b559e2724bb234fca05aa9c02c57407e04ee9dc3b454aa63fbff483a8b11de949624b9f1831a9612"},
+	{"6d9be1ee6ebd27a258115aad99b7317b9c8d28b6d76431c3", "horn tenant knee talent sponsor spell gate clip pulse soap slush warm silver nephew swap uncle crack brave", "fd579828af3da1d32544ce4db5c73d53fc8acc4ddb1e3b251a31179cdb71e853c56d2fcb11aed39898ce6c34b10b5382772db8796e52837b54468aeb312cfc3d"},
+	{"9f6a2878b2520799a44ef18bc7df394e7061a224d2c33cd015b157d746869863", "panda eyebrow bullet gorilla call smoke muffin taste mesh discover soft ostrich alcohol speed nation flash devote level hobby quick inner drive ghost inside", "72be8e052fc4919d2adf28d5306b5474b0069df35b02303de8c1729c9538dbb6fc2d731d5f832193cd9fb6aeecbc469594a70e3dd50811b5067f3b88b28c3e8d"},
+	{"23db8160a31d3e97dca3688e56df0f2a", "cat swing flag economy stadium episode income home mixture report sense fee", "306de968d4aea6dee8a5448dc4f71811a2173d9e3ecefcf8c3148a53ba2b181c80172be7ab7e9404b56cde50c6633e41ee57d4214a4d274089f352321cc6b73c"},
+	{"8197a4a47f0425faeaa69deebc05ca29c0a5b5cc76ceacc0", "light rule cinnamon wrap drastic word pride squirrel upgrade then income fatal apart sustain crack supply proud access", "4cbdff1ca2db800fd61cae72a57475fdc6bab03e441fd63f96dabd1f183ef5b782925f00105f318309a7e9c3ea6967c7801e46c8a58082674c860a37b93eda02"},
+	{"066dca1a2bb7e8a1db2832148ce9933eea0f3ac9548d793112d9a95c9407efad", "all hour make first leader extend hole alien behind guard gospel lava path output census museum junior mass reopen famous sing advance salt reform", "26e975ec644423f4a4c4f4215ef09b4bd7ef924e85d1d17c4cf3f136c2863cf6df0a475045652c57eb5fb41513ca2a2d67722b77e954b4b3fc11f7590449191d"},
+	{"f30f8c1da665478f49b001d94c5fc452", "vessel ladder alter error federal sibling chat ability sun glass valve picture", "2aaa9242daafcee6aa9d7269f17d4efe271e1b9a529178d7dc139cd18747090bf9d60295d0ce74309a78852a9caadf0af48aae1c6253839624076224374bc63f"},
+	{"c10ec20dc3cd9f652c7fac2f1230f7a3c828389a14392f05", "scissors invite lock maple supreme raw rapid void congress muscle digital elegant little brisk hair mango congress clump", "7b4a10be9d98e6cba265566db7f136718e1398c71cb581e1b2f464cac1ceedf4f3e274dc270003c670ad8d02c4558b2f8e39edea2775c9e232c7cb798b069e88"},
+	{"f585c11aec520db57dd353c69554b21a89b20fb0650966fa0a9d6f74fd989d8f", "void come effort suffer camp survey warrior heavy shoot primary clutch crush open amazing screen patrol group space point ten exist slush involve unfold", "01f5bced59dec48e362f2c45b5de68b9fd6c92c6634f44d6d40aab69056506f0e35524a518034ddc1192e1dacd32c1ed3eaa3c3b131c88ed8e7e54c49a5d0998"},
+}
+
+// slip10Vectors SLIP-0010 test vector 1, 種子 000102030405060708090a0b0c0d0e0f, 路徑 m/0H/1/2H/2/1000000000 的每一層
+var slip10Vectors = []struct {
+	t                         KeyType
+	path                      string
+	chainCode, key, publicKey string
+}{
+	{KeySecp256k1, "m", "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", "0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2"},
+	{KeySecp256k1, "m/0H", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", "035a784662a4a20a65bf6aab9ae98a6c068a81c52e4b032c0fb5400c706cfccc56"},
+	{KeySecp256k1, "m/0H/1", "2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", "03501e454bf00751f24b1b489aa925215d66af2234e3891c3b21a52bedb3cd711c"},
+	{KeySecp256k1, "m/0H/1/2H", "04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca", "0357bfe1e341d01c69fe5654309956cbea516822fba8a601743a012a7896ee8dc2"},
+	{KeySecp256k1, "m/0H/1/2H/2", "cfb71883f01676f587d023cc53a35bc7f88f724b1f8c2892ac1275ac822a3edd", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4", "02e8445082a72f29b75ca48748a914df60622a609cacfce8ed0e35804560741d29"},
+	{KeySecp256k1, "m/0H/1/2H/2/1000000000", "c783e67b921d2beb8f6b389cc646d7263b4145701dadd2161548a8b078e65e9e", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8", "022a471424da5e657499d1ff51cb43c47481a03b1e77f951fe64cec9f5a48f7011"},
+	{KeyP256, "m", "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea", "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2", "0266874dc6ade47b3ecd096745ca09bcd29638dd52c2c12117b11ed3e458cfa9e8"},
+	{KeyP256, "m/0H", "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11", "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c", "0384610f5ecffe8fda089363a41f56a5c7ffc1d81b59a612d0d649b2d22355590c"},
+	{KeyP256, "m/0H/1", "4187afff1aafa8445010097fb99d23aee9f599450c7bd140b6826ac22ba21d0c", "284e9d38d07d21e4e281b645089a94f4cf5a5a81369acf151a1c3a57f18b2129", "03526c63f8d0b4bbbf9c80df553fe66742df4676b241dabefdef67733e070f6844"},
+	{KeyP256, "m/0H/1/2H", "98c7514f562e64e74170cc3cf304ee1ce54d6b6da4f880f313e8204c2a185318", "694596e8a54f252c960eb771a3c41e7e32496d03b954aeb90f61635b8e092aa7", "0359cf160040778a4b14c5f4d7b76e327ccc8c4a6086dd9451b7482b5a4972dda0"},
+	{KeyP256, "m/0H/1/2H/2", "ba96f776a5c3907d7fd48bde5620ee374d4acfd540378476019eab70790c63a0", "5996c37fd3dd2679039b23ed6f70b506c6b56b3cb5e424681fb0fa64caf82aaa", "029f871f4cb9e1c97f9f4de9ccd0d4a2f2a171110c61178f84430062230833ff20"},
+	{KeyP256, "m/0H/1/2H/2/1000000000", "b9b7b82d326bb9cb5b5b121066feea4eb93d5241103c9e7a18aad40f1dde8059", "21c4f269ef0a5fd1badf47eeacebeeaa3de22eb8e5b0adcd0f27dd99d34d0119", "02216cd26d31147f72427a453c443ed2cde8a1e53c9cc44e5ddf739725413fe3f4"},
+}
+
+func TestBIP39Vectors(t *testing.T) {
+	for _, v := range bip39Vectors {
+		if got := entropyToMnemonic(mustHex(t, v.entropy)); got != v.mnemonic {
+			t.Errorf("entropy %s: mnemonic %q, want %q", v.entropy, got, v.mnemonic)
+		}
+		if err := ValidateMnemonic(v.mnemonic); err != nil {
+			t.Errorf("%q: %v", v.mnemonic, err)
+		}
+
+		seed, err := MnemonicSeed(v.mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(seed) != v.seed {
+			t.Errorf("%q: seed %x, want %s", v.mnemonic, seed, v.seed)
+		}
+	}
+}
+
+func TestValidateMnemonicRejects(t *testing.T) {
+	for _, mnemonic := range []string{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon bitcoin",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo",
+	} {
+		if err := ValidateMnemonic(mnemonic); err == nil {
+			t.Errorf("%q is accepted", mnemonic)
+		}
+	}
+}
+
+func TestSLIP10Vectors(t *testing.T) {
+	seed := mustHex(t, "000102030405060708090a0b0c0d0e0f")
+	path := []uint32{HardenedOffset, 1, 2 + HardenedOffset, 2, 1000000000}
+
+	for _, keyType := range []KeyType{KeySecp256k1, KeyP256} {
+		k, err := newMasterKey(keyType, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		depth := 0
+		for _, v := range slip10Vectors {
+			if v.t != keyType {
+				continue
+			}
+			if depth > 0 {
+				if k, err = k.child(keyType, path[depth-1]); err != nil {
+					t.Fatalf("%s %s: %v", keyType, v.path, err)
+				}
+			}
+			depth++
+
+			var pub ecdsa.PublicKey
+			pub.Curve = keyType.Curve()
+			pub.X, pub.Y = pub.Curve.ScalarBaseMult(bytes32(k.key))
+
+			if !bytes.Equal(k.chainCode, mustHex(t, v.chainCode)) {
+				t.Errorf("%s %s: chain code %x, want %s", keyType, v.path, k.chainCode, v.chainCode)
+			}
+			if !bytes.Equal(bytes32(k.key), mustHex(t, v.key)) {
+				t.Errorf("%s %s: private key %x, want %s", keyType, v.path, bytes32(k.key), v.key)
+			}
+			if !bytes.Equal(compressPublicKey(&pub), mustHex(t, v.publicKey)) {
+				t.Errorf("%s %s: public key %x, want %s", keyType, v.path, compressPublicKey(&pub), v.publicKey)
+			}
+		}
+		if depth != len(path)+1 {
+			t.Errorf("%s: checked %d levels", keyType, depth)
+		}
+	}
+}
+
+// TestRescanGapLimit 連續 gapLimit 個未使用的地址後停止, 之後才使用的地址不會被找到
+func TestRescanGapLimit(t *testing.T) {
+	seed, err := MnemonicSeed(bip39Vectors[0].mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newWallets := func() *Wallets {
+		ws := &Wallets{Wallets: make(map[string]*Wallet)}
+		if err := ws.InitHD(seed, KeySecp256k1); err != nil {
+			t.Fatal(err)
+		}
+		return ws
+	}
+
+	// 收款鏈 0, 5, 24 在 gap 之內, 50 在連續 20 個未使用的地址之後; 找零鏈只用了 2
+	used := make(map[string]bool)
+	ws := newWallets()
+	for _, u := range []struct{ chain, index uint32 }{
+		{ReceiveChain, 0}, {ReceiveChain, 5}, {ReceiveChain, 24}, {ReceiveChain, 50}, {ChangeChain, 2},
+	} {
+		w, err := ws.HD.derive(u.chain, u.index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		used[hex.EncodeToString(PublicKeyHash(w.Publickey))] = true
+	}
+	isUsed := func(pubKeyHash []byte) bool { return used[hex.EncodeToString(pubKeyHash)] }
+
+	found, err := ws.Rescan(isUsed, DefaultGapLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != 4 {
+		t.Errorf("found %d used addresses, want 4", found)
+	}
+	if ws.HD.Next != [2]uint32{25, 3} {
+		t.Errorf("next indexes %v, want [25 3]", ws.HD.Next)
+	}
+	// 最後一個已使用地址之前的地址都加回錢包
+	if len(ws.Wallets) != 25+3 || len(ws.HD.Paths) != 25+3 {
+		t.Errorf("%d addresses restored, want 28", len(ws.Wallets))
+	}
+	if !hasPath(ws, HDPath(ReceiveChain, 24)) || hasPath(ws, HDPath(ReceiveChain, 25)) {
+		t.Error("restored receive addresses do not end at the last used index")
+	}
+
+	ws = newWallets()
+	if found, err := ws.Rescan(isUsed, 4); err != nil || found != 2 {
+		t.Errorf("gap limit 4: found %d, %v, want 2", found, err)
+	}
+	if ws.HD.Next != [2]uint32{1, 3} {
+		t.Errorf("gap limit 4: next indexes %v, want [1 3]", ws.HD.Next)
+	}
+}
+
+func hasPath(ws *Wallets, path string) bool {
+	for _, p := range ws.HD.Paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
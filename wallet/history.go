@@ -0,0 +1,264 @@
+package wallet
+
+import (
+	"blockchain/params"
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+)
+
+// historyFile 錢包的交易紀錄, 與錢包檔案分開保存, 錢包鎖定時也能更新
+const historyFile = "./tmp/history_%s.data"
+
+// 交易紀錄的分類
+const (
+	CategoryReceive  = "receive"  // 收款
+	CategorySend     = "send"     // 付款
+	CategorySelf     = "self"     // 所有輸出都回到錢包, 只付了手續費
+	CategoryGenerate = "generate" // 挖礦獎勵
+)
+
+// HistoryOutput 交易中付給錢包的輸出
+type HistoryOutput struct {
+	Index       int
+	Address     string
+	Value       int
+	Change      bool   // 錢包自己付款的交易中回到錢包的輸出
+	SpentBy     []byte // 花費此輸出且已確認的交易
+	SpentHeight int
+}
+
+// HistoryTx 一筆與錢包有關的交易
+type HistoryTx struct {
+	ID        []byte
+	BlockHash []byte // 未確認時為空
+	Height    int    // 未確認時為 -1
+	Timestamp uint64
+	Coinbase  bool
+	Sent      int // 花費的錢包輸出總額
+	Fee       int // 只記錄錢包付款的交易
+	Outputs   []HistoryOutput
+	WatchOnly bool // 只涉及觀察地址
+}
+
+// Received 付給錢包且不是找零的金額
+func (t *HistoryTx) Received() int {
+	received := 0
+	for _, out := range t.Outputs {
+		if !out.Change {
+			received += out.Value
+		}
+	}
+	return received
+}
+
+// Change 回到錢包的找零
+func (t *HistoryTx) Change() int {
+	change := 0
+	for _, out := range t.Outputs {
+		if out.Change {
+			change += out.Value
+		}
+	}
+	return change
+}
+
+// Amount 交易對錢包餘額的影響, 付款為負值
+func (t *HistoryTx) Amount() int {
+	return t.Received() + t.Change() - t.Sent
+}
+
+// Category ...
+func (t *HistoryTx) Category() string {
+	switch {
+	case t.Coinbase:
+		return CategoryGenerate
+	case t.Sent == 0:
+		return CategoryReceive
+	case t.Amount() == -t.Fee:
+		return CategorySelf
+	}
+	return CategorySend
+}
+
+// Confirmations 以目前鏈高計算確認數, 未確認為 0
+func (t *HistoryTx) Confirmations(bestHeight int) int {
+	if t.Height < 0 {
+		return 0
+	}
+	return bestHeight - t.Height + 1
+}
+
+// HasAddress 交易是否付給此地址
+func (t *HistoryTx) HasAddress(address string) bool {
+	for _, out := range t.Outputs {
+		if out.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+// History 錢包的交易紀錄與已掃描到的鏈頂
+type History struct {
+	Tip    []byte // 最後掃描的區塊, 空值時不檢查 Height 上的區塊
+	Height int    // 最後掃描的高度, -1 為尚未掃描
+	Txs    map[string]*HistoryTx
+}
+
+// LoadHistory 讀取交易紀錄, 檔案不存在時回傳空的紀錄
+func LoadHistory(nodeID string) *History {
+	h := &History{Height: -1, Txs: make(map[string]*HistoryTx)}
+
+	content, err := ioutil.ReadFile(params.Active.Path(historyFile, walletID(nodeID, activeName)))
+	if os.IsNotExist(err) {
+		return h
+	}
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(h); err != nil {
+		log.Panic(err)
+	}
+	if h.Txs == nil {
+		h.Txs = make(map[string]*HistoryTx)
+	}
+	h.upgradeAddresses()
+	return h
+}
+
+// Save ...
+func (h *History) Save(nodeID string) {
+	var content bytes.Buffer
+
+	if err := gob.NewEncoder(&content).Encode(h); err != nil {
+		log.Panic(err)
+	}
+	if err := ioutil.WriteFile(params.Active.Path(historyFile, walletID(nodeID, activeName)), content.Bytes(), 0600); err != nil {
+		log.Panic(err)
+	}
+}
+
+// Rewind 移除 height 以上確認的紀錄與花費, 之後從 height 重新掃描
+func (h *History) Rewind(height int) {
+	if height < 0 {
+		height = 0
+	}
+	for id, t := range h.Txs {
+		if t.Height >= height {
+			delete(h.Txs, id)
+			continue
+		}
+		for i := range t.Outputs {
+			if t.Outputs[i].SpentBy != nil && t.Outputs[i].SpentHeight >= height {
+				t.Outputs[i].SpentBy = nil
+				t.Outputs[i].SpentHeight = 0
+			}
+		}
+	}
+
+	if height <= h.Height {
+		h.Tip = nil
+		h.Height = height - 1
+	}
+}
+
+// DropUnconfirmed 移除未確認的紀錄, 同步時依目前尚未確認的交易重新加入
+func (h *History) DropUnconfirmed() {
+	for id, t := range h.Txs {
+		if t.Height < 0 {
+			delete(h.Txs, id)
+		}
+	}
+}
+
+// Add 加入或取代紀錄; 已確認的紀錄會標記它花費的錢包輸出
+func (h *History) Add(t *HistoryTx, spends [][]byte, outs []int) {
+	h.Txs[hex.EncodeToString(t.ID)] = t
+	if t.Height < 0 {
+		return
+	}
+
+	for i, id := range spends {
+		prev, ok := h.Txs[hex.EncodeToString(id)]
+		if !ok {
+			continue
+		}
+		for j := range prev.Outputs {
+			if prev.Outputs[j].Index == outs[i] {
+				prev.Outputs[j].SpentBy = t.ID
+				prev.Outputs[j].SpentHeight = t.Height
+			}
+		}
+	}
+}
+
+// FindOutput 回傳紀錄中的錢包輸出
+func (h *History) FindOutput(id []byte, index int) (HistoryOutput, bool) {
+	t, ok := h.Txs[hex.EncodeToString(id)]
+	if !ok {
+		return HistoryOutput{}, false
+	}
+	for _, out := range t.Outputs {
+		if out.Index == index {
+			return out, true
+		}
+	}
+	return HistoryOutput{}, false
+}
+
+// Transactions 依高度排序的紀錄, 未確認的交易排在最後
+func (h *History) Transactions() []*HistoryTx {
+	var txs []*HistoryTx
+	for _, t := range h.Txs {
+		txs = append(txs, t)
+	}
+
+	height := func(t *HistoryTx) int {
+		if t.Height < 0 {
+			return int(^uint(0) >> 1)
+		}
+		return t.Height
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		if height(txs[i]) != height(txs[j]) {
+			return height(txs[i]) < height(txs[j])
+		}
+		return bytes.Compare(txs[i].ID, txs[j].ID) < 0
+	})
+	return txs
+}
+
+// Balances 每個地址已確認且未花費的金額
+func (h *History) Balances() map[string]int {
+	balances := make(map[string]int)
+	for _, t := range h.Txs {
+		if t.Height < 0 {
+			continue
+		}
+		for _, out := range t.Outputs {
+			if out.SpentBy == nil {
+				balances[out.Address] += out.Value
+			}
+		}
+	}
+	return balances
+}
+
+// KeyHashes 錢包中所有地址 (包括觀察地址) 的 public key hash (hex) 對應的地址
+func (ws *Wallets) KeyHashes() map[string]string {
+	owned := make(map[string]string)
+	for _, address := range ws.WatchedAddresses() {
+		_, pubKeyHash, err := DecodeAddress(address)
+		if err != nil {
+			log.Panic(err)
+		}
+		owned[hex.EncodeToString(pubKeyHash)] = address
+	}
+	return owned
+}
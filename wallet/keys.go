@@ -0,0 +1,107 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// KeyType 金鑰與簽章演算法, 同時決定輸出的鎖定類型
+type KeyType byte
+
+const (
+	KeyP256      KeyType = 0x00 // P256 ECDSA, 舊的錢包與輸出皆為此類型
+	KeySecp256k1 KeyType = 0x01 // secp256k1 ECDSA, 新錢包的預設類型
+	KeySchnorr   KeyType = 0x02 // secp256k1 BIP340 Schnorr
+
+	// DefaultKeyType createWallet 未指定時使用的類型
+	DefaultKeyType = KeySecp256k1
+)
+
+// PublicKeyLength SEC1 壓縮格式公鑰長度: 1 byte 前綴 (0x02 / 0x03) + 32 bytes X
+const PublicKeyLength = 33
+
+func (t KeyType) String() string {
+	switch t {
+	case KeyP256:
+		return "p256"
+	case KeySecp256k1:
+		return "secp256k1"
+	case KeySchnorr:
+		return "schnorr"
+	}
+	return fmt.Sprintf("unknown(%d)", byte(t))
+}
+
+// Valid ...
+func (t KeyType) Valid() bool {
+	return t == KeyP256 || t == KeySecp256k1 || t == KeySchnorr
+}
+
+// Curve 回傳此類型使用的曲線
+func (t KeyType) Curve() elliptic.Curve {
+	if t == KeyP256 {
+		return elliptic.P256()
+	}
+	return S256()
+}
+
+// ParseKeyType 解析 createWallet -type 參數
+func ParseKeyType(name string) (KeyType, error) {
+	for _, t := range []KeyType{KeyP256, KeySecp256k1, KeySchnorr} {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key type %q", name)
+}
+
+// EncodePublicKey 編碼公鑰: ECDSA 類型使用 SEC1 壓縮格式, Schnorr 使用 32 bytes x-only 格式
+func EncodePublicKey(t KeyType, pub *ecdsa.PublicKey) []byte {
+	if t == KeySchnorr {
+		return bytes32(pub.X)
+	}
+
+	return compressPublicKey(pub)
+}
+
+// compressPublicKey SEC1 壓縮格式
+func compressPublicKey(pub *ecdsa.PublicKey) []byte {
+	compressed := make([]byte, PublicKeyLength)
+	compressed[0] = byte(2 + pub.Y.Bit(0))
+	pub.X.FillBytes(compressed[1:])
+
+	return compressed
+}
+
+// DecodePublicKey 解析 ECDSA 類型的 SEC1 壓縮格式公鑰, 拒絕長度錯誤, 前綴錯誤或不在曲線上的點
+func DecodePublicKey(t KeyType, data []byte) (*ecdsa.PublicKey, error) {
+	if t != KeyP256 && t != KeySecp256k1 {
+		return nil, errors.New("key type has no SEC1 encoding")
+	}
+	if len(data) != PublicKeyLength || (data[0] != 0x02 && data[0] != 0x03) {
+		return nil, errors.New("public key is not SEC1 compressed")
+	}
+
+	curve := t.Curve()
+	if t == KeyP256 {
+		x, y := elliptic.UnmarshalCompressed(curve, data)
+		if x == nil {
+			return nil, errors.New("public key is not a valid curve point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	x := new(big.Int).SetBytes(data[1:])
+	y := S256().(*secp256k1Curve).liftX(x)
+	if y == nil {
+		return nil, errors.New("public key is not a valid curve point")
+	}
+	if y.Bit(0) != uint(data[0]&1) {
+		y.Sub(curve.Params().P, y)
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
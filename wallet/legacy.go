@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"blockchain/params"
+	"bytes"
+
+	"github.com/mr-tron/base58"
+)
+
+// upgradeAddress 把舊版 version 的地址轉換為目前網路的 version, 其他字串保持不變
+func upgradeAddress(address string) string {
+	payload, err := base58.Decode(address)
+	if err != nil || len(payload) < 1+checksumLength {
+		return address
+	}
+
+	checksum := payload[len(payload)-checksumLength:]
+	payload = payload[:len(payload)-checksumLength]
+	if payload[0] != params.Active.LegacyAddressVersion || !bytes.Equal(checksum, CheckSum(payload)) {
+		return address
+	}
+
+	payload = append([]byte{params.Active.AddressVersion}, payload[1:]...)
+	return string(Base58Encode(append(payload, CheckSum(payload)...)))
+}
+
+func upgradeKeys(m map[string]bool) map[string]bool {
+	if m == nil {
+		return nil
+	}
+	upgraded := make(map[string]bool, len(m))
+	for address, v := range m {
+		upgraded[upgradeAddress(address)] = v
+	}
+	return upgraded
+}
+
+// upgradeAddresses 錢包檔案以地址為索引, 載入舊版檔案時轉換所有地址; 下次 SaveFile 時寫入新的地址
+func (ws *Wallets) upgradeAddresses() {
+	wallets := make(map[string]*Wallet, len(ws.Wallets))
+	for address, w := range ws.Wallets {
+		wallets[upgradeAddress(address)] = w
+	}
+	ws.Wallets = wallets
+
+	ws.Watch = upgradeKeys(ws.Watch)
+
+	// 鎖定時 IsWatched 由加密檔案中的公鑰判斷
+	if ws.encrypted != nil && ws.encrypted.Public != nil {
+		public := make(map[string]publicKey, len(ws.encrypted.Public))
+		for address, pub := range ws.encrypted.Public {
+			public[upgradeAddress(address)] = pub
+		}
+		ws.encrypted.Public = public
+	}
+
+	if ws.Labels != nil {
+		labels := make(map[string]string, len(ws.Labels))
+		for address, label := range ws.Labels {
+			labels[upgradeAddress(address)] = label
+		}
+		ws.Labels = labels
+	}
+	for name, address := range ws.Contacts {
+		ws.Contacts[name] = upgradeAddress(address)
+	}
+
+	if ws.HD != nil && ws.HD.Paths != nil {
+		paths := make(map[string]string, len(ws.HD.Paths))
+		for address, path := range ws.HD.Paths {
+			paths[upgradeAddress(address)] = path
+		}
+		ws.HD.Paths = paths
+	}
+}
+
+// upgradeAddresses 交易紀錄中的地址
+func (h *History) upgradeAddresses() {
+	for _, t := range h.Txs {
+		for i := range t.Outputs {
+			t.Outputs[i].Address = upgradeAddress(t.Outputs[i].Address)
+		}
+	}
+}
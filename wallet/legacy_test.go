@@ -0,0 +1,45 @@
+package wallet
+
+import (
+	"blockchain/params"
+	"testing"
+)
+
+// legacyAddress 以舊版 version 編碼的 P256 地址
+func legacyAddress(pubKeyHash []byte) string {
+	payload := append([]byte{params.Active.LegacyAddressVersion}, pubKeyHash...)
+	return string(Base58Encode(append(payload, CheckSum(payload)...)))
+}
+
+// TestUpgradeLockedAddresses 鎖定的加密錢包載入舊版地址後, 以新地址查詢仍能判斷觀察地址
+func TestUpgradeLockedAddresses(t *testing.T) {
+	watched := MakeWallet(KeyP256)
+	owned := MakeWallet(KeyP256)
+	watchedOld := legacyAddress(PublicKeyHash(watched.Publickey))
+	ownedOld := legacyAddress(PublicKeyHash(owned.Publickey))
+
+	e := &encryptedWallets{Public: map[string]publicKey{
+		watchedOld: {KeyP256, watched.Publickey, true},
+		ownedOld:   {KeyP256, owned.Publickey, false},
+	}}
+	ws := &Wallets{encrypted: e, Wallets: e.lockedWallets()}
+	ws.upgradeAddresses()
+
+	watchedNew := EncodeAddress(KeyP256, PublicKeyHash(watched.Publickey))
+	ownedNew := EncodeAddress(KeyP256, PublicKeyHash(owned.Publickey))
+	if watchedNew == watchedOld {
+		t.Fatal("legacy and current addresses are identical")
+	}
+	if !ws.IsLocked() {
+		t.Fatal("wallet is not locked")
+	}
+	if !ws.IsWatched(watchedNew) {
+		t.Error("upgraded watch-only address is not watched")
+	}
+	if ws.IsWatched(ownedNew) {
+		t.Error("upgraded address with a private key is watched")
+	}
+	if _, ok := ws.Wallets[ownedNew]; !ok {
+		t.Error("upgraded address is missing from the wallet")
+	}
+}
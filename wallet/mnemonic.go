@@ -0,0 +1,107 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// BIP39 種子的 PBKDF2 參數
+const (
+	mnemonicIterations = 2048
+	mnemonicSeedLength = 64
+)
+
+var errInvalidMnemonic = errors.New("mnemonic is not valid")
+
+// NewMnemonic 產生 BIP39 助記詞, words 可為 12, 15, 18, 21 或 24
+func NewMnemonic(words int) (string, error) {
+	if words < 12 || words > 24 || words%3 != 0 {
+		return "", fmt.Errorf("mnemonic must have 12, 15, 18, 21 or 24 words, not %d", words)
+	}
+
+	// 每 3 個單字對應 32 bits 的亂數與 1 bit 的檢查碼
+	entropy := make([]byte, words/3*4)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+
+	return entropyToMnemonic(entropy), nil
+}
+
+// entropyToMnemonic 在亂數後附加 SHA-256 的前 len/32 bits, 每 11 bits 對應一個單字
+func entropyToMnemonic(entropy []byte) string {
+	checksumBits := len(entropy) / 4
+	hash := sha256.Sum256(entropy)
+
+	bits := new(big.Int).SetBytes(entropy)
+	bits.Lsh(bits, uint(checksumBits))
+	bits.Or(bits, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	words := make([]string, (len(entropy)*8+checksumBits)/11)
+	mask := big.NewInt(2047)
+	for i := len(words) - 1; i >= 0; i-- {
+		index := new(big.Int).And(bits, mask)
+		words[i] = englishWords[index.Int64()]
+		bits.Rsh(bits, 11)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// ValidateMnemonic 檢查單字數, 單字是否在單字表中以及檢查碼
+func ValidateMnemonic(mnemonic string) error {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return errInvalidMnemonic
+	}
+
+	bits := new(big.Int)
+	for _, word := range words {
+		index := wordIndex(word)
+		if index < 0 {
+			return fmt.Errorf("%q is not a mnemonic word", word)
+		}
+		bits.Lsh(bits, 11)
+		bits.Or(bits, big.NewInt(int64(index)))
+	}
+
+	checksumBits := len(words) / 3
+	checksum := new(big.Int).And(bits, big.NewInt(int64(1)<<uint(checksumBits)-1))
+	bits.Rsh(bits, uint(checksumBits))
+
+	entropy := make([]byte, checksumBits*4)
+	bits.FillBytes(entropy)
+
+	hash := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(hash[0]>>(8-checksumBits)) {
+		return errInvalidMnemonic
+	}
+
+	return nil
+}
+
+func wordIndex(word string) int {
+	for i, w := range englishWords {
+		if w == word {
+			return i
+		}
+	}
+	return -1
+}
+
+// MnemonicSeed BIP39 種子: PBKDF2-HMAC-SHA512, salt 為 "mnemonic" 加上可選的密碼
+func MnemonicSeed(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), mnemonicIterations, mnemonicSeedLength, sha512.New), nil
+}
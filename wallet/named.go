@@ -0,0 +1,192 @@
+package wallet
+
+import (
+	"blockchain/params"
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// loadedFile 節點已載入的具名錢包
+const loadedFile = "./tmp/loaded_wallets_%s.data"
+
+// activeName 目前使用的具名錢包, 空字串為節點的預設錢包
+var activeName string
+
+// walletNamePattern 名稱不可包含 '_', 避免與網路名稱後綴混淆
+var walletNamePattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,32}$`)
+
+// ValidateWalletName ...
+func ValidateWalletName(name string) error {
+	if !walletNamePattern.MatchString(name) {
+		return fmt.Errorf("wallet name %q must be 1-32 letters, digits or '-'", name)
+	}
+	return nil
+}
+
+// nodeIDPattern nodeID 也是節點的埠號, 只能是數字
+var nodeIDPattern = regexp.MustCompile(`^[0-9]{1,5}$`)
+
+// ValidateNodeID nodeID 不含 '-', 節點 "3000" 的具名錢包 "ops" 才不會與節點 "3000-ops" 的預設錢包使用同一個檔案
+func ValidateNodeID(nodeID string) error {
+	if !nodeIDPattern.MatchString(nodeID) {
+		return fmt.Errorf("node ID %q must be a port number", nodeID)
+	}
+	return nil
+}
+
+// walletID 具名錢包的檔案以 nodeID-name 區分, 預設錢包維持原本的檔名; nodeID 須先通過 ValidateNodeID
+func walletID(nodeID, name string) string {
+	if name == "" {
+		return nodeID
+	}
+	return nodeID + "-" + name
+}
+
+func walletPath(nodeID, name string) string {
+	return params.Active.Path(walletFile, walletID(nodeID, name))
+}
+
+func loadedWallets(nodeID string) (map[string]bool, error) {
+	loaded := make(map[string]bool)
+
+	content, err := ioutil.ReadFile(params.Active.Path(loadedFile, nodeID))
+	if os.IsNotExist(err) {
+		return loaded, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = gob.NewDecoder(bytes.NewReader(content)).Decode(&loaded)
+	return loaded, err
+}
+
+func saveLoadedWallets(nodeID string, loaded map[string]bool) error {
+	var content bytes.Buffer
+
+	if err := gob.NewEncoder(&content).Encode(loaded); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(params.Active.Path(loadedFile, nodeID), content.Bytes(), 0600)
+}
+
+// Select 之後的錢包操作使用具名錢包, 名稱必須已載入; 空字串為預設錢包
+func Select(nodeID, name string) error {
+	if name == "" {
+		activeName = ""
+		return nil
+	}
+	if err := ValidateWalletName(name); err != nil {
+		return err
+	}
+
+	loaded, err := loadedWallets(nodeID)
+	if err != nil {
+		return err
+	}
+	if !loaded[name] {
+		return fmt.Errorf("wallet %q is not loaded, run loadWallet first", name)
+	}
+
+	activeName = name
+	return nil
+}
+
+// CreateNamedWallet 建立空的具名錢包並載入
+func CreateNamedWallet(nodeID, name string) error {
+	if err := ValidateWalletName(name); err != nil {
+		return err
+	}
+	path := walletPath(nodeID, name)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("wallet %q already exists", name)
+	}
+
+	ws := Wallets{Wallets: make(map[string]*Wallet)}
+	ws.saveTo(path)
+
+	return LoadWallet(nodeID, name)
+}
+
+// LoadWallet 載入已存在的具名錢包, 之後可以用 -wallet 選擇
+func LoadWallet(nodeID, name string) error {
+	if err := ValidateWalletName(name); err != nil {
+		return err
+	}
+	if _, err := os.Stat(walletPath(nodeID, name)); err != nil {
+		return fmt.Errorf("wallet %q does not exist", name)
+	}
+
+	loaded, err := loadedWallets(nodeID)
+	if err != nil {
+		return err
+	}
+	loaded[name] = true
+	return saveLoadedWallets(nodeID, loaded)
+}
+
+// UnloadWallet 卸載具名錢包並結束它的解鎖期間, 檔案保留
+func UnloadWallet(nodeID, name string) error {
+	loaded, err := loadedWallets(nodeID)
+	if err != nil {
+		return err
+	}
+	if !loaded[name] {
+		return fmt.Errorf("wallet %q is not loaded", name)
+	}
+
+	delete(loaded, name)
+	if err := saveLoadedWallets(nodeID, loaded); err != nil {
+		return err
+	}
+	return lockSession(nodeID, name)
+}
+
+// WalletInfo ...
+type WalletInfo struct {
+	Name   string
+	Loaded bool
+}
+
+// ListWallets 節點在目前網路上所有的具名錢包, 依名稱排序
+func ListWallets(nodeID string) ([]WalletInfo, error) {
+	loaded, err := loadedWallets(nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := walletPath(nodeID, "*")
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// 由檔名取出名稱; 其他網路的檔案名稱含有 '_' 而被排除
+	base := filepath.Base(pattern)
+	star := strings.Index(base, "*")
+	var wallets []WalletInfo
+	for _, match := range matches {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), base[:star]), base[star+1:])
+		if ValidateWalletName(name) != nil {
+			continue
+		}
+		wallets = append(wallets, WalletInfo{name, loaded[name]})
+	}
+
+	sort.Slice(wallets, func(i, j int) bool {
+		return wallets[i].Name < wallets[j].Name
+	})
+	return wallets, nil
+}
+
+// ActiveWalletName 目前使用的錢包名稱, 空字串為預設錢包
+func ActiveWalletName() string {
+	return activeName
+}
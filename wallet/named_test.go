@@ -0,0 +1,30 @@
+package wallet
+
+import "testing"
+
+func TestValidateNodeID(t *testing.T) {
+	for _, nodeID := range []string{"3000", "1", "65535"} {
+		if err := ValidateNodeID(nodeID); err != nil {
+			t.Errorf("%q: %v", nodeID, err)
+		}
+	}
+	for _, nodeID := range []string{"", "3000-ops", "3000_testnet", "../3000", "123456"} {
+		if err := ValidateNodeID(nodeID); err == nil {
+			t.Errorf("%q is accepted", nodeID)
+		}
+	}
+}
+
+// TestWalletIDDistinct 不同的 nodeID 與錢包名稱不會對應到同一個檔案
+func TestWalletIDDistinct(t *testing.T) {
+	seen := make(map[string][2]string)
+	for _, nodeID := range []string{"3000", "3001", "30"} {
+		for _, name := range []string{"", "ops", "00", "0-ops", "1"} {
+			id := walletID(nodeID, name)
+			if prev, ok := seen[id]; ok {
+				t.Errorf("node %s wallet %q and node %s wallet %q share %s", nodeID, name, prev[0], prev[1], id)
+			}
+			seen[id] = [2]string{nodeID, name}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package wallet
+
+import (
+	"blockchain/params"
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/mr-tron/base58"
+)
+
+// EncodePrivateKey 以 Base58Check 匯出私鑰, version 依網路而定且與地址不同, 格式與地址相同:
+// P256 為 version + 32 bytes 私鑰, 其他類型在 version 之後多一個類型位元組
+func EncodePrivateKey(w Wallet) (string, error) {
+	if w.IsWatchOnly() {
+		return "", ErrWatchOnly
+	}
+
+	payload := []byte{params.Active.PrivateKeyVersion}
+	if w.Type != KeyP256 {
+		payload = append(payload, byte(w.Type))
+	}
+	payload = append(payload, bytes32(w.PrivateKey.D)...)
+
+	return string(Base58Encode(append(payload, CheckSum(payload)...))), nil
+}
+
+// DecodePrivateKey 解析 EncodePrivateKey 的輸出, 回傳對應的錢包
+func DecodePrivateKey(encoded string) (*Wallet, error) {
+	payload, err := base58.Decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < 1+checksumLength {
+		return nil, errors.New("private key is too short")
+	}
+
+	actualChecksum := payload[len(payload)-checksumLength:]
+	payload = payload[:len(payload)-checksumLength]
+	if !bytes.Equal(actualChecksum, CheckSum(payload)) {
+		return nil, errors.New("private key checksum mismatch")
+	}
+	// 舊版匯出的私鑰 (與 Bitcoin WIF 相同的 version) 仍可匯入, 匯出時一律使用新的 version
+	if payload[0] != params.Active.PrivateKeyVersion && payload[0] != params.Active.LegacyPrivateKeyVersion {
+		if p := params.ByPrivateKeyVersion(payload[0]); p != nil {
+			return nil, fmt.Errorf("private key belongs to %s, not %s", p.Name, params.Active.Name)
+		}
+		return nil, errors.New("private key has unknown version")
+	}
+
+	t := KeyP256
+	switch len(payload) {
+	case 1 + 32:
+	case 2 + 32:
+		t = KeyType(payload[1])
+		if !t.Valid() || t == KeyP256 {
+			return nil, errors.New("private key has unknown key type")
+		}
+	default:
+		return nil, errors.New("private key has wrong length")
+	}
+
+	d := new(big.Int).SetBytes(payload[len(payload)-32:])
+	if d.Sign() == 0 || d.Cmp(t.Curve().Params().N) >= 0 {
+		return nil, errors.New("private key is out of range")
+	}
+
+	return walletFromKey(t, d), nil
+}
+
+// ImportPrivateKey 加入私鑰, 已存在的觀察項目升級為可簽署的錢包
+func (ws *Wallets) ImportPrivateKey(encoded string) (string, error) {
+	if ws.IsLocked() {
+		return "", ErrWalletLocked
+	}
+
+	w, err := DecodePrivateKey(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	address := string(w.Address())
+	if old, ok := ws.Wallets[address]; ok && !old.IsWatchOnly() {
+		return "", fmt.Errorf("%s is already in the wallet", address)
+	}
+
+	delete(ws.Watch, address)
+	ws.Wallets[address] = w
+	return address, nil
+}
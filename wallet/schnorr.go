@@ -0,0 +1,124 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// SchnorrSignatureLength BIP340 簽章長度: 32 bytes R.x + 32 bytes s
+const SchnorrSignatureLength = 64
+
+// taggedHash BIP340 的 tagged hash: sha256(sha256(tag) || sha256(tag) || msg)
+func taggedHash(tag string, msg ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, m := range msg {
+		h.Write(m)
+	}
+
+	return h.Sum(nil)
+}
+
+func bytes32(n *big.Int) []byte {
+	b := make([]byte, 32)
+	return n.FillBytes(b)
+}
+
+// SchnorrSign 以 BIP340 規則簽署 32 bytes 的摘要
+func SchnorrSign(privKey *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	aux := make([]byte, 32)
+	if _, err := rand.Read(aux); err != nil {
+		return nil, err
+	}
+
+	return schnorrSignWithAux(privKey, digest, aux)
+}
+
+// schnorrSignWithAux aux 為 BIP340 的 32 bytes auxiliary random data, 固定 aux 時簽章也固定
+func schnorrSignWithAux(privKey *ecdsa.PrivateKey, digest, aux []byte) ([]byte, error) {
+	curve := S256().(*secp256k1Curve)
+	n := curve.N
+
+	if privKey.Curve != S256() {
+		return nil, errors.New("schnorr signatures require a secp256k1 key")
+	}
+	if len(digest) != 32 {
+		return nil, errors.New("schnorr digest must be 32 bytes")
+	}
+	if len(aux) != 32 {
+		return nil, errors.New("schnorr auxiliary data must be 32 bytes")
+	}
+
+	d := new(big.Int).Set(privKey.D)
+	px, py := curve.ScalarBaseMult(bytes32(d))
+	if py.Bit(0) == 1 {
+		d.Sub(n, d)
+	}
+
+	t := bytes32(d)
+	auxHash := taggedHash("BIP0340/aux", aux)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+
+	k := new(big.Int).SetBytes(taggedHash("BIP0340/nonce", t, bytes32(px), digest))
+	k.Mod(k, n)
+	if k.Sign() == 0 {
+		return nil, errors.New("schnorr nonce is zero")
+	}
+
+	rx, ry := curve.ScalarBaseMult(bytes32(k))
+	if ry.Bit(0) == 1 {
+		k.Sub(n, k)
+	}
+
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", bytes32(rx), bytes32(px), digest))
+	e.Mod(e, n)
+
+	s := new(big.Int).Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, n)
+
+	return append(bytes32(rx), bytes32(s)...), nil
+}
+
+// SchnorrVerify 以 BIP340 規則驗證簽章, pubKey 為 32 bytes 的 x-only 公鑰
+func SchnorrVerify(pubKey, digest, sig []byte) bool {
+	curve := S256().(*secp256k1Curve)
+
+	if len(pubKey) != 32 || len(digest) != 32 || len(sig) != SchnorrSignatureLength {
+		return false
+	}
+
+	px := new(big.Int).SetBytes(pubKey)
+	py := curve.liftX(px)
+	if py == nil {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(curve.P) >= 0 || s.Cmp(curve.N) >= 0 {
+		return false
+	}
+
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", sig[:32], pubKey, digest))
+	e.Mod(e, curve.N)
+	e.Sub(curve.N, e)
+
+	sx, sy, sz := curve.jacobianFromAffine(curve.ScalarBaseMult(sig[32:]))
+	ex, ey, ez := curve.jacobianFromAffine(curve.ScalarMult(px, py, bytes32(e)))
+	rx, ry := curve.affineFromJacobian(curve.addJacobian(sx, sy, sz, ex, ey, ez))
+
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return false
+	}
+
+	return ry.Bit(0) == 0 && rx.Cmp(r) == 0
+}
@@ -0,0 +1,233 @@
+package wallet
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+)
+
+// secp256k1 曲線 y² = x³ + 7, 標準庫的 CurveParams 假設 a = -3 無法直接使用
+type secp256k1Curve struct {
+	*elliptic.CurveParams
+}
+
+var (
+	initS256 sync.Once
+	s256     *secp256k1Curve
+)
+
+// S256 回傳 secp256k1 曲線
+func S256() elliptic.Curve {
+	initS256.Do(func() {
+		params := &elliptic.CurveParams{Name: "secp256k1", BitSize: 256}
+		params.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F", 16)
+		params.N, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+		params.B = big.NewInt(7)
+		params.Gx, _ = new(big.Int).SetString("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", 16)
+		params.Gy, _ = new(big.Int).SetString("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", 16)
+		s256 = &secp256k1Curve{params}
+	})
+
+	return s256
+}
+
+func (c *secp256k1Curve) Params() *elliptic.CurveParams {
+	return c.CurveParams
+}
+
+// polynomial 計算 x³ + 7 mod p
+func (c *secp256k1Curve) polynomial(x *big.Int) *big.Int {
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+	x3.Add(x3, c.B)
+
+	return x3.Mod(x3, c.P)
+}
+
+func (c *secp256k1Curve) IsOnCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(c.P) >= 0 || y.Sign() < 0 || y.Cmp(c.P) >= 0 {
+		return false
+	}
+
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, c.P)
+
+	return c.polynomial(x).Cmp(y2) == 0
+}
+
+// liftX 由 x 座標計算偶數的 y, x 不在曲線上時回傳 nil
+func (c *secp256k1Curve) liftX(x *big.Int) *big.Int {
+	if x.Sign() < 0 || x.Cmp(c.P) >= 0 {
+		return nil
+	}
+
+	y := new(big.Int).ModSqrt(c.polynomial(x), c.P)
+	if y == nil {
+		return nil
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(c.P, y)
+	}
+
+	return y
+}
+
+// 以 Jacobian 座標 (X, Y, Z) 表示點, 對應的仿射座標為 (X/Z², Y/Z³), Z = 0 為無窮遠點
+
+func (c *secp256k1Curve) affineFromJacobian(x, y, z *big.Int) (*big.Int, *big.Int) {
+	if z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+
+	zinv := new(big.Int).ModInverse(z, c.P)
+	zinv2 := new(big.Int).Mul(zinv, zinv)
+
+	xOut := new(big.Int).Mul(x, zinv2)
+	xOut.Mod(xOut, c.P)
+	zinv2.Mul(zinv2, zinv)
+	yOut := new(big.Int).Mul(y, zinv2)
+	yOut.Mod(yOut, c.P)
+
+	return xOut, yOut
+}
+
+func (c *secp256k1Curve) jacobianFromAffine(x, y *big.Int) (*big.Int, *big.Int, *big.Int) {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+
+	return new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)
+}
+
+func (c *secp256k1Curve) doubleJacobian(x, y, z *big.Int) (*big.Int, *big.Int, *big.Int) {
+	if z.Sign() == 0 || y.Sign() == 0 {
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+
+	p := c.P
+	a := new(big.Int).Mul(x, x)
+	a.Mod(a, p)
+	b := new(big.Int).Mul(y, y)
+	b.Mod(b, p)
+	cc := new(big.Int).Mul(b, b)
+	cc.Mod(cc, p)
+
+	d := new(big.Int).Add(x, b)
+	d.Mul(d, d)
+	d.Sub(d, a)
+	d.Sub(d, cc)
+	d.Lsh(d, 1)
+	d.Mod(d, p)
+
+	e := new(big.Int).Lsh(a, 1)
+	e.Add(e, a)
+	f := new(big.Int).Mul(e, e)
+
+	x3 := new(big.Int).Sub(f, new(big.Int).Lsh(d, 1))
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(d, x3)
+	y3.Mul(y3, e)
+	y3.Sub(y3, new(big.Int).Lsh(cc, 3))
+	y3.Mod(y3, p)
+
+	z3 := new(big.Int).Mul(y, z)
+	z3.Lsh(z3, 1)
+	z3.Mod(z3, p)
+
+	return x3, y3, z3
+}
+
+func (c *secp256k1Curve) addJacobian(x1, y1, z1, x2, y2, z2 *big.Int) (*big.Int, *big.Int, *big.Int) {
+	if z1.Sign() == 0 {
+		return new(big.Int).Set(x2), new(big.Int).Set(y2), new(big.Int).Set(z2)
+	}
+	if z2.Sign() == 0 {
+		return new(big.Int).Set(x1), new(big.Int).Set(y1), new(big.Int).Set(z1)
+	}
+
+	p := c.P
+	z1z1 := new(big.Int).Mul(z1, z1)
+	z1z1.Mod(z1z1, p)
+	z2z2 := new(big.Int).Mul(z2, z2)
+	z2z2.Mod(z2z2, p)
+
+	u1 := new(big.Int).Mul(x1, z2z2)
+	u1.Mod(u1, p)
+	u2 := new(big.Int).Mul(x2, z1z1)
+	u2.Mod(u2, p)
+
+	s1 := new(big.Int).Mul(y1, z2)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, p)
+	s2 := new(big.Int).Mul(y2, z1)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, p)
+
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, p)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, p)
+
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return c.doubleJacobian(x1, y1, z1)
+		}
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+
+	hh := new(big.Int).Mul(h, h)
+	hh.Mod(hh, p)
+	hhh := new(big.Int).Mul(hh, h)
+	hhh.Mod(hhh, p)
+	v := new(big.Int).Mul(u1, hh)
+	v.Mod(v, p)
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, hhh)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	s1.Mul(s1, hhh)
+	y3.Sub(y3, s1)
+	y3.Mod(y3, p)
+
+	z3 := new(big.Int).Mul(z1, z2)
+	z3.Mul(z3, h)
+	z3.Mod(z3, p)
+
+	return x3, y3, z3
+}
+
+func (c *secp256k1Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	jx1, jy1, jz1 := c.jacobianFromAffine(x1, y1)
+	jx2, jy2, jz2 := c.jacobianFromAffine(x2, y2)
+
+	return c.affineFromJacobian(c.addJacobian(jx1, jy1, jz1, jx2, jy2, jz2))
+}
+
+func (c *secp256k1Curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	return c.affineFromJacobian(c.doubleJacobian(c.jacobianFromAffine(x1, y1)))
+}
+
+func (c *secp256k1Curve) ScalarMult(bx, by *big.Int, k []byte) (*big.Int, *big.Int) {
+	px, py, pz := c.jacobianFromAffine(bx, by)
+	x, y, z := new(big.Int), new(big.Int), new(big.Int)
+
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			x, y, z = c.doubleJacobian(x, y, z)
+			if (b>>uint(bit))&1 == 1 {
+				x, y, z = c.addJacobian(x, y, z, px, py, pz)
+			}
+		}
+	}
+
+	return c.affineFromJacobian(x, y, z)
+}
+
+func (c *secp256k1Curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.Gx, c.Gy, k)
+}
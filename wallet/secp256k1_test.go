@@ -0,0 +1,193 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func hexInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("bad hex %q", s)
+	}
+	return n
+}
+
+// s256Multiples k·G 的已知值
+var s256Multiples = []struct {
+	k, x, y string
+}{
+	{"1", "79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", "483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8"},
+	{"2", "C6047F9441ED7D6D3045406E95C07CD85C778E4B8CEF3CA7ABAC09B95C709EE5", "1AE168FEA63DC339A3C58419466CEAEEF7F632653266D0E1236431A950CFE52A"},
+	{"3", "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9", "388F7B0F632DE8140FE337E62A37F3566500A99934C2231B6CB9FD7584B8E672"},
+	{"4", "E493DBF1C10D80F3581E4904930B1404CC6C13900EE0758474FA94ABE8C4CD13", "51ED993EA0D455B75642E2098EA51448D967AE33BFBDFE40CFE97BDC47739922"},
+	{"5", "2F8BDE4D1A07209355B4A7250A5C5128E88B84BDDC619AB7CBA8D569B240EFE4", "D8AC222636E5E3D6D4DBA9DDA6C9C426F788271BAB0D6840DCA87D3AA6AC62D6"},
+	{"A", "A0434D9E47F3C86235477C7B1AE6AE5D3442D49B1943C2B752A68E2A47E247C7", "893ABA425419BC27A3B6C7E693A24C696F794C2ED877A1593CBEE53B037368D7"},
+	{"14", "4CE119C96E2FA357200B559B2F7DD5A5F02D5290AFF74B03F3E471B273211C97", "12BA26DCB10EC1625DA61FA10A844C676162948271D96967450288EE9233DC3A"},
+	{"18EBBB95EED0E13", "A90CC3D3F3E146DAADFC74CA1372207CB4B725AE708CEF713A98EDD73D99EF29", "5A79D6B289610C68BC3B47F3D72F9788A26A06868B4D8E433E1E2AD76FB7DC76"},
+	{"AA5E28D6A97A2479A65527F7290311A3624D4CC0FA1578598EE3C2613BF99522", "34F9460F0E4F08393D192B3C5133A6BA099AA0AD9FD54EBCCFACDFA239FF49C6", "0B71EA9BD730FD8923F6D25A7A91E7DD7728A960686CB5A901BB419E0F2CA232"},
+	{"7E2B897B8CEBC6361663AD410835639826D590F393D90A9538881735256DFAE3", "D74BF844B0862475103D96A611CF2D898447E288D34B360BC885CB8CE7C00575", "131C670D414C4546B88AC3FF664611B1C38CEB1C21D76369D7A7A0969D61D97D"},
+	{"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364140", "79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", "B7C52588D95C3B9AA25B0403F1EEF75702E84BB7597AABE663B82F6F04EF2777"},
+}
+
+func TestS256ScalarBaseMult(t *testing.T) {
+	curve := S256()
+	params := curve.Params()
+
+	for _, m := range s256Multiples {
+		k := hexInt(t, m.k)
+		wantX, wantY := hexInt(t, m.x), hexInt(t, m.y)
+
+		x, y := curve.ScalarBaseMult(k.Bytes())
+		if x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0 {
+			t.Errorf("%s·G = (%X, %X), want (%s, %s)", m.k, x, y, m.x, m.y)
+		}
+		if !curve.IsOnCurve(x, y) {
+			t.Errorf("%s·G is not on the curve", m.k)
+		}
+
+		x, y = curve.ScalarMult(params.Gx, params.Gy, bytes32(k))
+		if x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0 {
+			t.Errorf("ScalarMult(G, %s) does not match ScalarBaseMult", m.k)
+		}
+	}
+}
+
+func TestS256GroupLaw(t *testing.T) {
+	curve := S256()
+	params := curve.Params()
+	gx, gy := params.Gx, params.Gy
+
+	x2, y2 := curve.Double(gx, gy)
+	if x2.Cmp(hexInt(t, s256Multiples[1].x)) != 0 || y2.Cmp(hexInt(t, s256Multiples[1].y)) != 0 {
+		t.Error("G + G is not 2·G")
+	}
+
+	x3, y3 := curve.Add(gx, gy, x2, y2)
+	if x3.Cmp(hexInt(t, s256Multiples[2].x)) != 0 || y3.Cmp(hexInt(t, s256Multiples[2].y)) != 0 {
+		t.Error("G + 2·G is not 3·G")
+	}
+
+	// a·G + b·G = (a+b)·G
+	a, b := hexInt(t, s256Multiples[8].k), hexInt(t, s256Multiples[9].k)
+	ax, ay := curve.ScalarBaseMult(a.Bytes())
+	bx, by := curve.ScalarBaseMult(b.Bytes())
+	sum := new(big.Int).Add(a, b)
+	sum.Mod(sum, params.N)
+	wantX, wantY := curve.ScalarBaseMult(sum.Bytes())
+	if x, y := curve.Add(ax, ay, bx, by); x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0 {
+		t.Error("a·G + b·G is not (a+b)·G")
+	}
+
+	// 無窮遠點以 (0, 0) 表示
+	negY := new(big.Int).Sub(params.P, gy)
+	if x, y := curve.Add(gx, gy, gx, negY); x.Sign() != 0 || y.Sign() != 0 {
+		t.Error("G + (-G) is not the point at infinity")
+	}
+	if x, y := curve.ScalarBaseMult(params.N.Bytes()); x.Sign() != 0 || y.Sign() != 0 {
+		t.Error("n·G is not the point at infinity")
+	}
+	if x, y := curve.Add(gx, gy, new(big.Int), new(big.Int)); x.Cmp(gx) != 0 || y.Cmp(gy) != 0 {
+		t.Error("G + infinity is not G")
+	}
+}
+
+func TestS256IsOnCurve(t *testing.T) {
+	curve := S256()
+	params := curve.Params()
+
+	cases := []struct {
+		name string
+		x, y *big.Int
+	}{
+		{"y + 1", params.Gx, new(big.Int).Add(params.Gy, big.NewInt(1))},
+		{"x + p", new(big.Int).Add(params.Gx, params.P), params.Gy},
+		{"negative y", params.Gx, new(big.Int).Neg(params.Gy)},
+		{"infinity", new(big.Int), new(big.Int)},
+	}
+	for _, c := range cases {
+		if curve.IsOnCurve(c.x, c.y) {
+			t.Errorf("%s is on the curve", c.name)
+		}
+	}
+}
+
+func TestS256LiftX(t *testing.T) {
+	curve := S256().(*secp256k1Curve)
+
+	for _, m := range s256Multiples {
+		x, y := hexInt(t, m.x), hexInt(t, m.y)
+		lifted := curve.liftX(x)
+		if lifted == nil || lifted.Bit(0) != 0 {
+			t.Fatalf("liftX(%s) = %v", m.x, lifted)
+		}
+		if lifted.Cmp(y) != 0 && new(big.Int).Sub(curve.P, lifted).Cmp(y) != 0 {
+			t.Errorf("liftX(%s) is not ±y", m.x)
+		}
+	}
+
+	// x³ + 7 不是平方數, 以及 x ≥ p
+	for _, x := range []string{
+		"4A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D",
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F",
+	} {
+		if y := curve.liftX(hexInt(t, x)); y != nil {
+			t.Errorf("liftX(%s) = %X, want no point", x, y)
+		}
+	}
+}
+
+func TestS256PublicKeyEncoding(t *testing.T) {
+	curve := S256()
+
+	for _, m := range s256Multiples {
+		pub := &ecdsa.PublicKey{Curve: curve, X: hexInt(t, m.x), Y: hexInt(t, m.y)}
+
+		encoded := EncodePublicKey(KeySecp256k1, pub)
+		x, _ := hex.DecodeString(m.x)
+		if !bytes.Equal(encoded[1:], x) || encoded[0] != byte(2+pub.Y.Bit(0)) {
+			t.Fatalf("%s·G encodes to %x", m.k, encoded)
+		}
+
+		decoded, err := DecodePublicKey(KeySecp256k1, encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.X.Cmp(pub.X) != 0 || decoded.Y.Cmp(pub.Y) != 0 {
+			t.Errorf("%s·G does not survive compression", m.k)
+		}
+	}
+
+	notOnCurve, _ := hex.DecodeString("024A298DACAE57395A15D0795DDBFD1DCB564DA82B0F269BC70A74F8220429BA1D")
+	if _, err := DecodePublicKey(KeySecp256k1, notOnCurve); err == nil {
+		t.Error("decoded an x coordinate that is not on the curve")
+	}
+}
+
+func TestS256ECDSA(t *testing.T) {
+	privKey, err := ecdsa.GenerateKey(S256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("secp256k1"))
+
+	r, s, err := ecdsa.Sign(rand.Reader, privKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.Verify(&privKey.PublicKey, digest[:], r, s) {
+		t.Fatal("signature does not verify")
+	}
+
+	digest[0] ^= 1
+	if ecdsa.Verify(&privKey.PublicKey, digest[:], r, s) {
+		t.Error("signature verifies for another digest")
+	}
+}
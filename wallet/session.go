@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"blockchain/params"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"time"
+)
+
+// sessionSocket 解鎖代理程式的 socket; 金鑰只保存在代理程式的記憶體中, 不寫入檔案
+const sessionSocket = "./tmp/wallets_%s.sock"
+
+// 代理程式的要求
+const (
+	sessionGetKey byte = 'k'
+	sessionLock   byte = 'l'
+)
+
+// Session 解鎖期間的代理程式, 到期或 lockWallet 時清除金鑰並結束, 不需要其他命令執行
+type Session struct {
+	listener net.Listener
+	key      []byte
+}
+
+func sessionPath(nodeID, name string) string {
+	return params.Active.Path(sessionSocket, walletID(nodeID, name))
+}
+
+// NewSession 以密碼解鎖目前的錢包並建立 socket, 取代已存在的解鎖期間
+func NewSession(nodeID, passphrase string) (*Session, error) {
+	ws, err := CreateWallets(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := Lock(nodeID); err != nil {
+		return nil, err
+	}
+	if err := ws.Unlock(passphrase); err != nil {
+		return nil, err
+	}
+
+	path := sessionPath(nodeID, activeName)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &Session{listener, ws.key}, nil
+}
+
+// Serve 提供金鑰給其他命令, timeout 後或收到鎖定要求時關閉 socket 並清除金鑰
+func (s *Session) Serve(timeout time.Duration) {
+	timer := time.AfterFunc(timeout, func() { s.listener.Close() })
+	defer timer.Stop()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			break
+		}
+		s.handle(conn)
+	}
+
+	for i := range s.key {
+		s.key[i] = 0
+	}
+}
+
+func (s *Session) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	request := make([]byte, 1)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+	switch request[0] {
+	case sessionGetKey:
+		conn.Write(s.key)
+	case sessionLock:
+		// 先關閉 socket, 要求者收到回應後不會再取得金鑰
+		s.listener.Close()
+	}
+}
+
+// requestSession 連接 name 錢包的代理程式; 沒有代理程式時移除殘留的 socket 並回傳 ErrWalletLocked
+func requestSession(nodeID, name string, request byte) ([]byte, error) {
+	path := sessionPath(nodeID, name)
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		if _, statErr := os.Stat(path); statErr == nil {
+			os.Remove(path)
+		}
+		return nil, ErrWalletLocked
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	if _, err := conn.Write([]byte{request}); err != nil {
+		return nil, err
+	}
+	if request != sessionGetKey {
+		_, err := ioutil.ReadAll(conn)
+		return nil, err
+	}
+
+	key := make([]byte, scryptKeyLen)
+	if _, err := io.ReadFull(conn, key); err != nil {
+		return nil, ErrWalletLocked
+	}
+	return key, nil
+}
+
+// lockSession 結束 name 錢包的解鎖期間
+func lockSession(nodeID, name string) error {
+	_, err := requestSession(nodeID, name, sessionLock)
+	if errors.Is(err, ErrWalletLocked) {
+		return nil
+	}
+	return err
+}
+
+// Lock 結束目前錢包的解鎖期間
+func Lock(nodeID string) error {
+	return lockSession(nodeID, activeName)
+}
+
+// resumeSession 解鎖期間內由代理程式取得金鑰並解鎖
+func (ws *Wallets) resumeSession(nodeID string) {
+	key, err := requestSession(nodeID, activeName, sessionGetKey)
+	if err != nil {
+		return
+	}
+	if err := ws.unlockWithKey(key); err != nil {
+		Lock(nodeID)
+	}
+}
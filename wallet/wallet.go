@@ -1,20 +1,23 @@
 package wallet
 
 import (
+	"blockchain/params"
 	"bytes"
 	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/gob"
+	"errors"
 	"fmt"
 	"log"
+	"math/big"
 
+	"github.com/mr-tron/base58"
 	"golang.org/x/crypto/ripemd160"
 )
 
 const (
 	checksumLength = 4
-	version        = byte(0x00)
 )
 
 // Wallet ...
@@ -24,16 +27,13 @@ const (
 type Wallet struct {
 	PrivateKey ecdsa.PrivateKey
 	Publickey  []byte
+	Type       KeyType
 }
 
 func (w Wallet) Address() []byte {
 	pubHash := PublicKeyHash(w.Publickey)
 	fmt.Println(pubHash)
-	versionedHash := append([]byte{version}, pubHash...)
-	checksum := CheckSum(versionedHash)
-
-	fullHash := append(versionedHash, checksum...)
-	address := Base58Encode(fullHash)
+	address := encodeBase58Address(w.Type, pubHash)
 
 	fmt.Printf("pub key: %x\n", w.Publickey)
 	fmt.Printf("pub hash: %x\n", pubHash)
@@ -42,25 +42,104 @@ func (w Wallet) Address() []byte {
 	return address
 }
 
-func NewKeyPair() (ecdsa.PrivateKey, []byte) {
-	curve := elliptic.P256()
+// Bech32Address 同一把金鑰的 Bech32 地址
+func (w Wallet) Bech32Address() string {
+	return EncodeBech32Address(w.Type, PublicKeyHash(w.Publickey))
+}
+
+// EncodeAddress 由輸出的類型與 public key hash 產生 Base58Check 地址
+func EncodeAddress(t KeyType, pubKeyHash []byte) string {
+	return string(encodeBase58Address(t, pubKeyHash))
+}
+
+func encodeBase58Address(t KeyType, pubHash []byte) []byte {
+	version := params.Active.AddressVersion
+	versionedHash := append([]byte{version}, pubHash...)
+	if t != KeyP256 {
+		versionedHash = append([]byte{version, byte(t)}, pubHash...)
+	}
+	checksum := CheckSum(versionedHash)
+
+	fullHash := append(versionedHash, checksum...)
+	return Base58Encode(fullHash)
+}
+
+func NewKeyPair(t KeyType) (ecdsa.PrivateKey, []byte) {
+	curve := t.Curve()
 
 	private, err := ecdsa.GenerateKey(curve, rand.Reader)
 	if err != nil {
 		log.Panic(err)
 	}
 
-	pub := append(private.PublicKey.X.Bytes(), private.PublicKey.Y.Bytes()...)
+	pub := EncodePublicKey(t, &private.PublicKey)
 
 	return *private, pub
 }
 
-func MakeWallet() *Wallet {
-	private, public := NewKeyPair()
-	w := Wallet{private, public}
+func MakeWallet(t KeyType) *Wallet {
+	private, public := NewKeyPair(t)
+	w := Wallet{private, public, t}
 	return &w
 }
 
+// walletData 錢包檔案中每把金鑰的儲存格式, 只保存類型與私鑰純量, 公鑰於讀取時重新計算;
+// 觀察錢包沒有私鑰, 改為保存公鑰
+type walletData struct {
+	Type      KeyType
+	D         []byte
+	Publickey []byte
+}
+
+// GobEncode ...
+func (w Wallet) GobEncode() ([]byte, error) {
+	var content bytes.Buffer
+
+	wd := walletData{Type: w.Type}
+	if w.IsWatchOnly() {
+		wd.Publickey = w.Publickey
+	} else {
+		wd.D = w.PrivateKey.D.Bytes()
+	}
+
+	err := gob.NewEncoder(&content).Encode(wd)
+	return content.Bytes(), err
+}
+
+// GobDecode ...
+func (w *Wallet) GobDecode(data []byte) error {
+	var wd walletData
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&wd); err != nil {
+		return err
+	}
+	if !wd.Type.Valid() {
+		return errors.New("wallet file contains an unknown key type")
+	}
+
+	if len(wd.D) == 0 {
+		if err := ValidatePublicKey(wd.Type, wd.Publickey); err != nil {
+			return err
+		}
+		*w = Wallet{Publickey: wd.Publickey, Type: wd.Type}
+		return nil
+	}
+
+	*w = *walletFromKey(wd.Type, new(big.Int).SetBytes(wd.D))
+
+	return nil
+}
+
+// walletFromKey 由私鑰純量建立錢包, 公鑰重新計算
+func walletFromKey(t KeyType, d *big.Int) *Wallet {
+	curve := t.Curve()
+	priv := ecdsa.PrivateKey{D: d}
+	priv.Curve = curve
+	priv.X, priv.Y = curve.ScalarBaseMult(d.Bytes())
+
+	return &Wallet{priv, EncodePublicKey(t, &priv.PublicKey), t}
+}
+
 func PublicKeyHash(pubKey []byte) []byte {
 	pubHash := sha256.Sum256(pubKey)
 
@@ -82,12 +161,56 @@ func CheckSum(payload []byte) []byte {
 	return secondHash[:checksumLength]
 }
 
+// DecodeAddress 解析 Base58Check 或 Bech32 地址, 回傳金鑰類型與 public key hash
+// P256 地址為 version + hash, 其他類型在 version 之後多一個類型位元組
+func DecodeAddress(address string) (KeyType, []byte, error) {
+	if isBech32Address(address) {
+		return decodeBech32Address(address)
+	}
+
+	payload, err := base58.Decode(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(payload) < 1+checksumLength {
+		return 0, nil, errors.New("address is too short")
+	}
+
+	actualChecksum := payload[len(payload)-checksumLength:]
+	payload = payload[:len(payload)-checksumLength]
+	if !bytes.Equal(actualChecksum, CheckSum(payload)) {
+		return 0, nil, errors.New("address checksum mismatch")
+	}
+	if payload[0] != params.Active.AddressVersion {
+		if p := params.ByAddressVersion(payload[0]); p != nil {
+			return 0, nil, fmt.Errorf("address belongs to %s, not %s", p.Name, params.Active.Name)
+		}
+		if payload[0] == params.Active.LegacyAddressVersion {
+			return 0, nil, errors.New("address uses the retired version, ask for a new address")
+		}
+		return 0, nil, errors.New("address has unknown version")
+	}
+
+	switch len(payload) {
+	case 1 + ripemd160.Size:
+		return KeyP256, payload[1:], nil
+	case 2 + ripemd160.Size:
+		t := KeyType(payload[1])
+		if !t.Valid() || t == KeyP256 {
+			return 0, nil, errors.New("address has unknown key type")
+		}
+		return t, payload[2:], nil
+	}
+	return 0, nil, errors.New("address has wrong length")
+}
+
+// NormalizeAddress 轉換為 Base58Check 格式, 錢包檔案以此格式作為索引
+func NormalizeAddress(address string) (string, error) {
+	return FormatAddress(address, AddressBase58)
+}
+
 func ValidateAddress(address string) bool {
-	pubKeyHash := Base58Decode([]byte(address))
-	actualChecksum := pubKeyHash[len(pubKeyHash)-checksumLength:]
-	version := pubKeyHash[0]
-	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-checksumLength]
-	targetChecksum := CheckSum(append([]byte{version}, pubKeyHash...))
+	_, _, err := DecodeAddress(address)
 
-	return bytes.Compare(actualChecksum, targetChecksum) == 0
+	return err == nil
 }
@@ -2,7 +2,6 @@ package wallet
 
 import (
 	"bytes"
-	"crypto/elliptic"
 	"encoding/gob"
 	"fmt"
 	"io/ioutil"
@@ -15,7 +14,14 @@ const (
 )
 
 type Wallets struct {
-	Wallets map[string]*Wallet
+	Wallets  map[string]*Wallet
+	HD       *HDChain          // 不為 nil 時新地址由種子導出
+	Watch    map[string]bool   // 只有地址的觀察項目
+	Labels   map[string]string // 錢包地址 -> 標籤
+	Contacts map[string]string // 聯絡人名稱 -> 外部地址
+
+	encrypted *encryptedWallets // 不為 nil 時檔案以密碼加密
+	key       []byte            // 解鎖後由密碼導出的金鑰
 }
 
 func CreateWallets(nodeID string) (*Wallets, error) {
@@ -23,11 +29,22 @@ func CreateWallets(nodeID string) (*Wallets, error) {
 	wallets.Wallets = make(map[string]*Wallet)
 
 	err := wallets.LoadFile(nodeID)
+	if err == nil && wallets.IsLocked() {
+		wallets.resumeSession(nodeID)
+	}
 	return &wallets, err
 }
 
 func (ws Wallets) GetWallet(address string) Wallet {
-	return *ws.Wallets[address]
+	return *ws.Wallets[normalizeKey(address)]
+}
+
+// normalizeKey 錢包以 Base58Check 地址為索引, Bech32 地址先轉換; 無效的地址保持不變
+func normalizeKey(address string) string {
+	if normalized, err := NormalizeAddress(address); err == nil {
+		return normalized
+	}
+	return address
 }
 
 func (ws *Wallets) GetAllAddresses() []string {
@@ -39,8 +56,13 @@ func (ws *Wallets) GetAllAddresses() []string {
 	return addresses
 }
 
-func (ws *Wallets) AddWallet() string {
-	wallet := MakeWallet()
+// AddWallet 新增收款地址, HD 錢包由收款鏈導出, 其他類型或非 HD 錢包產生隨機金鑰
+func (ws *Wallets) AddWallet(t KeyType) string {
+	if ws.HD != nil && ws.HD.Type == t {
+		return ws.addHDWallet(ReceiveChain)
+	}
+
+	wallet := MakeWallet(t)
 
 	address := string(wallet.Address())
 
@@ -52,7 +74,7 @@ func (ws *Wallets) AddWallet() string {
 func (ws *Wallets) LoadFile(nodeID string) error {
 	var (
 		wallet     Wallets
-		walletFile string = fmt.Sprintf(walletFile, nodeID)
+		walletFile string = walletPath(nodeID, activeName)
 	)
 
 	fmt.Println(walletFile)
@@ -65,7 +87,21 @@ func (ws *Wallets) LoadFile(nodeID string) error {
 		return err
 	}
 
-	gob.Register(elliptic.P256())
+	// 加密的檔案在解鎖前只載入公鑰
+	if bytes.HasPrefix(fileContent, encryptedMagic) {
+		encrypted, err := decodeEncrypted(fileContent)
+		if err != nil {
+			return err
+		}
+		ws.encrypted = encrypted
+		ws.Wallets = encrypted.lockedWallets()
+		ws.Watch = encrypted.Watch
+		ws.Labels = encrypted.Labels
+		ws.Contacts = encrypted.Contacts
+		ws.upgradeAddresses()
+		return nil
+	}
+
 	decoder := gob.NewDecoder(bytes.NewReader(fileContent))
 
 	err = decoder.Decode(&wallet)
@@ -74,22 +110,31 @@ func (ws *Wallets) LoadFile(nodeID string) error {
 	}
 
 	ws.Wallets = wallet.Wallets
+	ws.HD = wallet.HD
+	ws.Watch = wallet.Watch
+	ws.Labels = wallet.Labels
+	ws.Contacts = wallet.Contacts
+	ws.upgradeAddresses()
 
 	return nil
 }
 
+// SaveFile 檔案只有擁有者可以讀寫, 加密的錢包必須先解鎖
 func (ws *Wallets) SaveFile(nodeID string) {
-	var content bytes.Buffer
-	walletFile := fmt.Sprintf(walletFile, nodeID)
-	gob.Register(elliptic.P256())
+	ws.saveTo(walletPath(nodeID, activeName))
+}
 
-	encoder := gob.NewEncoder(&content)
-	err := encoder.Encode(ws)
+func (ws *Wallets) saveTo(walletFile string) {
+	content, err := ws.encode()
 	if err != nil {
 		log.Panic(err)
 	}
 
-	err = ioutil.WriteFile(walletFile, content.Bytes(), 0644)
+	err = ioutil.WriteFile(walletFile, content, 0600)
+	if err != nil {
+		log.Panic(err)
+	}
+	err = os.Chmod(walletFile, 0600)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// ErrWatchOnly 觀察地址沒有私鑰, 不能簽署
+var ErrWatchOnly = errors.New("address is watch-only, the wallet has no private key for it")
+
+// IsWatchOnly 只有公鑰的錢包; 鎖定的加密錢包在解鎖前也沒有私鑰
+func (w Wallet) IsWatchOnly() bool {
+	return w.PrivateKey.D == nil
+}
+
+// ValidatePublicKey 檢查公鑰編碼與是否在曲線上
+func ValidatePublicKey(t KeyType, pub []byte) error {
+	if t != KeySchnorr {
+		_, err := DecodePublicKey(t, pub)
+		return err
+	}
+
+	if len(pub) != 32 || S256().(*secp256k1Curve).liftX(new(big.Int).SetBytes(pub)) == nil {
+		return errors.New("public key is not a valid x-only key")
+	}
+	return nil
+}
+
+// ImportPublicKey 加入只有公鑰的觀察錢包, 可查詢餘額與建立未簽署交易
+func (ws *Wallets) ImportPublicKey(t KeyType, pub []byte) (string, error) {
+	if !t.Valid() {
+		return "", fmt.Errorf("unknown key type %s", t)
+	}
+	if err := ValidatePublicKey(t, pub); err != nil {
+		return "", err
+	}
+
+	w := &Wallet{Publickey: pub, Type: t}
+	address := string(w.Address())
+	if _, ok := ws.Wallets[address]; ok {
+		return "", fmt.Errorf("%s is already in the wallet", address)
+	}
+
+	ws.Wallets[address] = w
+	return address, nil
+}
+
+// ImportAddress 加入只有地址的觀察項目, 沒有公鑰所以只能查詢餘額
+func (ws *Wallets) ImportAddress(address string) error {
+	address, err := NormalizeAddress(address)
+	if err != nil {
+		return errors.New("address is not valid")
+	}
+	if _, ok := ws.Wallets[address]; ok || ws.Watch[address] {
+		return fmt.Errorf("%s is already in the wallet", address)
+	}
+
+	if ws.Watch == nil {
+		ws.Watch = make(map[string]bool)
+	}
+	ws.Watch[address] = true
+	return nil
+}
+
+// IsWatched 地址是否為觀察地址 (只有地址或只有公鑰)
+func (ws *Wallets) IsWatched(address string) bool {
+	address = normalizeKey(address)
+	if ws.Watch[address] {
+		return true
+	}
+	if ws.IsLocked() {
+		return ws.encrypted.Public[address].WatchOnly
+	}
+	w, ok := ws.Wallets[address]
+	return ok && w.IsWatchOnly()
+}
+
+// WatchedAddresses 所有地址, 包括只有地址的觀察項目, 依字母排序
+func (ws *Wallets) WatchedAddresses() []string {
+	addresses := ws.GetAllAddresses()
+	for address := range ws.Watch {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// SigningAddresses 擁有私鑰的地址
+func (ws *Wallets) SigningAddresses() []string {
+	var addresses []string
+	for address, w := range ws.Wallets {
+		if !w.IsWatchOnly() {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// GetSigningWallet 取得可以簽署的錢包, 觀察地址, 鎖定或不在錢包中的地址回傳錯誤
+func (ws *Wallets) GetSigningWallet(address string) (Wallet, error) {
+	if ws.IsLocked() {
+		return Wallet{}, ErrWalletLocked
+	}
+	address = normalizeKey(address)
+	w, ok := ws.Wallets[address]
+	if !ok {
+		if ws.Watch[address] {
+			return Wallet{}, ErrWatchOnly
+		}
+		return Wallet{}, fmt.Errorf("%s is not in the wallet", address)
+	}
+	if ws.IsWatched(address) {
+		return Wallet{}, ErrWatchOnly
+	}
+	return *w, nil
+}